@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/koeng101/gluau/vm"
+)
+
+// Compares copying (Bytes) and zero-copy (WithBytes) access to large strings and buffers
+func benchmarkByteAccess() {
+	fmt.Println("--- Byte access, 1MB payload (1K iterations) ---")
+
+	const size = 1 << 20
+	const iterations = 1000
+
+	luavm, err := vm.CreateLuaVm()
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		return
+	}
+	defer luavm.Close()
+
+	payload := make([]byte, size)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	str, err := luavm.CreateStringBytes(payload)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		return
+	}
+	defer str.Close()
+	buf, err := luavm.CreateBuffer(payload)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		return
+	}
+	defer buf.Close()
+
+	checksum := func(b []byte) int {
+		sum := 0
+		for _, c := range b {
+			sum += int(c)
+		}
+		return sum
+	}
+
+	for _, target := range []struct {
+		name     string
+		copying  func() []byte
+		zeroCopy func(func([]byte) error) error
+	}{
+		{"LuaString", str.Bytes, str.WithBytes},
+		{"LuaBuffer", buf.Bytes, buf.WithBytes},
+	} {
+		start := time.Now()
+		sum := 0
+		for i := 0; i < iterations; i++ {
+			sum += checksum(target.copying())
+		}
+		copyTime := time.Since(start)
+		fmt.Printf("%s.Bytes:     %v (checksum: %d)\n", target.name, copyTime, sum)
+
+		start = time.Now()
+		sum = 0
+		for i := 0; i < iterations; i++ {
+			_ = target.zeroCopy(func(b []byte) error {
+				sum += checksum(b)
+				return nil
+			})
+		}
+		viewTime := time.Since(start)
+		fmt.Printf("%s.WithBytes: %v (checksum: %d)\n", target.name, viewTime, sum)
+		fmt.Printf("=> WithBytes is %.2fx faster\n", float64(copyTime)/float64(viewTime))
+	}
+	fmt.Println()
+}
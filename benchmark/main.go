@@ -48,6 +48,9 @@ func main() {
 
 	// Benchmark 5: Table operations
 	benchmarkTables()
+
+	// Benchmark 6: Copying vs zero-copy access to strings and buffers
+	benchmarkByteAccess()
 }
 
 func benchmarkFibonacci() {
@@ -1,3 +1,7 @@
 module github.com/koeng101/gluau
 
 go 1.20
+
+require github.com/fsnotify/fsnotify v1.8.0
+
+require golang.org/x/sys v0.13.0 // indirect
@@ -1,21 +1,29 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"math"
 	"os"
 	"path"
+	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	// Import to ensure callback package is initialized
 	vmlib "github.com/koeng101/gluau/vm"
 	"github.com/koeng101/gluau/vmutils"
+	"github.com/koeng101/gluau/vmutils/bundle"
 	"github.com/koeng101/gluau/vmutils/require"
+	"github.com/koeng101/gluau/vmutils/stream"
 )
 
 // #include <stdlib.h>
@@ -388,8 +396,171 @@ func main() {
 	} else {
 		fmt.Println("got ret:", ret[0].(*vmlib.ValueInteger).Value())
 	}
+	info, err := luaFunc.Info()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to get Lua function info: %v", err))
+	}
+	if info.IsGoFunction() || info.NumParams != 0 || !info.IsVararg {
+		panic(fmt.Sprintf("Unexpected Lua function info: %+v", info))
+	}
+	fmt.Printf("Lua function info: %+v\n", info)
 	luaFunc.Close()
 
+	infoChunk, err := vm.LoadChunk(vmlib.ChunkOpts{
+		Name: "info_test",
+		Code: "local offset = 1\nlocal scale = 2\n\nlocal function adjust(a, b)\n\treturn (a + b + offset) * scale\nend\nreturn adjust",
+	})
+	if err != nil {
+		panic(err)
+	}
+	infoRet := vmutils.Must(infoChunk.Call())
+	namedInfo := vmutils.Must(infoRet[0].(*vmlib.ValueFunction).Value().Info())
+	if namedInfo.Name != "adjust" || namedInfo.LineDefined != 4 || namedInfo.NumParams != 2 || namedInfo.NumUpvalues != 2 || namedInfo.IsVararg || namedInfo.IsGoFunction() {
+		panic(fmt.Sprintf("Unexpected named Lua function info: %+v", namedInfo))
+	}
+	infoChunk.Close()
+	goInfoFn := vmutils.Must(vm.CreateFunction(func(funcVm *vmlib.CallbackLua, args []vmlib.Value) ([]vmlib.Value, error) {
+		return nil, nil
+	}))
+	goInfo := vmutils.Must(goInfoFn.Info())
+	if !goInfo.IsGoFunction() || goInfo.LineDefined != -1 {
+		panic(fmt.Sprintf("Unexpected Go function info: %+v", goInfo))
+	}
+	goInfoFn.Close()
+
+	tbFunc, err := vm.LoadChunk(vmlib.ChunkOpts{
+		Name: "traceback_test",
+		Code: "local function inner() error('boom') end\nlocal function outer() inner() end\nouter()",
+	})
+	if err != nil {
+		panic(err)
+	}
+	_, err = tbFunc.CallWithTraceback()
+	var tbErr *vmlib.TracebackError
+	if !errors.As(err, &tbErr) || len(tbErr.Frames) == 0 {
+		panic(fmt.Sprintf("Expected a traceback error, got: %v", err))
+	}
+	fmt.Println("Traceback error:", tbErr)
+	tbFunc.Close()
+
+	// GC API
+	if err := vm.GCCollect(); err != nil {
+		panic(fmt.Sprintf("Failed to run GC: %v", err))
+	}
+	gcStats, err := vm.GCStats()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to get GC stats: %v", err))
+	}
+	if gcStats.ManualCollections != 1 || gcStats.HeapSize <= 0 || gcStats.CategoryUsage[0] <= 0 {
+		panic(fmt.Sprintf("Unexpected GC stats: heap=%d collections=%d", gcStats.HeapSize, gcStats.ManualCollections))
+	}
+	// Small steps do not finish a cycle, so after the first one the collector is past the pause state
+	if finished := vmutils.Must(vm.GCStep(1)) || vmutils.Must(vm.GCStep(1)); !finished {
+		if state := vmutils.Must(vm.GCStats()).State; state == vmlib.GCStatePause || state > vmlib.GCStateSweep {
+			panic(fmt.Sprintf("Unexpected GC state after a step: %v", state))
+		}
+	}
+	fmt.Println("GC stats: heap size", gcStats.HeapSize, "running", gcStats.Running, "state", gcStats.State)
+
+	// Memory categories
+	pluginCat, err := vm.RegisterMemoryCategory("plugin")
+	if err != nil {
+		panic(fmt.Sprintf("Failed to register memory category: %v", err))
+	}
+	allocFunc, err := vm.LoadChunk(vmlib.ChunkOpts{
+		Name: "memcat_test",
+		Code: "local t = {} for i = 1, 1000 do t[i] = tostring(i) end return t",
+	})
+	if err != nil {
+		panic(err)
+	}
+	allocThread, err := vm.CreateThread(allocFunc)
+	if err != nil {
+		panic(err)
+	}
+	if err := allocThread.SetMemoryCategory(pluginCat); err != nil {
+		panic(fmt.Sprintf("Failed to set memory category: %v", err))
+	}
+	allocRets, err := allocThread.Resume()
+	if err != nil {
+		panic(err)
+	}
+	byCategory := vm.MemoryByCategory()
+	if byCategory["plugin"] <= 0 {
+		panic(fmt.Sprintf("Expected memory to be attributed to plugin category, got %v", byCategory))
+	}
+	fmt.Println("Memory by category:", byCategory)
+	for _, v := range allocRets {
+		v.Close()
+	}
+	allocThread.Close()
+	allocFunc.Close()
+
+	// A limit error caught by the script must not be attached to later, unrelated errors
+	vmutils.MustOk(vm.SetMemoryCategoryLimit(pluginCat, 1))
+	caughtFunc := vmutils.Must(vm.LoadChunk(vmlib.ChunkOpts{
+		Name: "memcat_caught",
+		Code: "local ok = pcall(function() for i = 1, 1e7 do end end) return ok",
+	}))
+	_, _ = caughtFunc.Call() // May fail again once the pcall returns, the limit is still exceeded
+	caughtFunc.Close()
+	vmutils.MustOk(vm.SetMemoryCategoryLimit(pluginCat, 0))
+	unrelatedFunc := vmutils.Must(vm.LoadChunk(vmlib.ChunkOpts{
+		Name: "memcat_unrelated",
+		Code: "error('unrelated')",
+	}))
+	_, err = unrelatedFunc.Call()
+	var staleLimitErr *vmlib.MemoryCategoryLimitError
+	if err == nil || errors.As(err, &staleLimitErr) {
+		panic(fmt.Sprintf("Expected an unrelated error without a limit error cause, got %v", err))
+	}
+	unrelatedFunc.Close()
+
+	// Handle tracking
+	trackedVm := vmutils.Must(vmlib.CreateLuaVm())
+	leakReported := false
+	trackedVm.EnableHandleTracking(func(leaked []vmlib.TrackedHandle) {
+		leakReported = true
+		if len(leaked) != 1 || leaked[0].Type != "LuaTable" {
+			panic(fmt.Sprintf("Expected exactly one leaked LuaTable, got %v", leaked))
+		}
+		fmt.Println("Leaked handle (expected):", leaked[0])
+	})
+	vmutils.Must(trackedVm.CreateString("closed")).Close()
+	leakedTable := vmutils.Must(trackedVm.CreateTable()) // Intentionally leaked
+	if counts := trackedVm.HandleCounts(); counts["LuaTable"] != 1 || counts["LuaString"] != 0 {
+		panic(fmt.Sprintf("Unexpected handle counts: %v", counts))
+	}
+	trackedVm.Close()
+	runtime.KeepAlive(leakedTable)
+	if !leakReported {
+		panic("Expected the leaked LuaTable to be reported when the VM is closed")
+	}
+
+	// Handles created in callbacks and closed by the garbage collector are reported as well
+	gcTrackedVm := vmutils.Must(vmlib.CreateLuaVm())
+	var gcLeaked []vmlib.TrackedHandle
+	gcTrackedVm.EnableHandleTracking(func(leaked []vmlib.TrackedHandle) {
+		gcLeaked = leaked
+	})
+	leakingFn := vmutils.Must(gcTrackedVm.CreateFunction(func(funcVm *vmlib.CallbackLua, args []vmlib.Value) ([]vmlib.Value, error) {
+		vmutils.Must(funcVm.MainState().CreateTable()) // Intentionally leaked
+		return nil, nil
+	}))
+	vmutils.Must(leakingFn.Call())
+	leakingFn.Close()
+	for i := 0; i < 20 && gcTrackedVm.HandleCounts()["LuaTable"] != 0; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond) // Finalizers run on their own goroutine
+	}
+	if counts := gcTrackedVm.HandleCounts(); counts["LuaTable"] != 0 {
+		panic(fmt.Sprintf("Expected the leaked LuaTable to be garbage collected, got handle counts %v", counts))
+	}
+	gcTrackedVm.Close()
+	if len(gcLeaked) != 1 || gcLeaked[0].Type != "LuaTable" {
+		panic(fmt.Sprintf("Expected the garbage collected LuaTable to be reported as leaked, got %v", gcLeaked))
+	}
+
 	udMt, err := vm.CreateTable()
 	if err != nil {
 		panic(fmt.Sprintf("Failed to create Lua table for userdata metatable: %v", err))
@@ -730,8 +901,364 @@ func main() {
 		panic("Returned user data pointer does not match original user data pointer")
 	}
 
+	// Reflection based userdata bindings
+	vmutils.Must(vmutils.BindType[bindVec](vmutils.BindOptions{TypeName: "Vec"}))
+	playerType := vmutils.Must(vmutils.BindType[bindPlayer](vmutils.BindOptions{TypeName: "Player"}))
+	player := &bindPlayer{Name: "alice", Health: 100, Secret: "hidden", Pos: bindVec{X: 3, Y: 4}, Tags: []string{"a", "b"}}
+	playerUd := vmutils.Must(playerType.Create(vm3, player))
+	bindTest := vmutils.Must(vm3.LoadChunk(vmlib.ChunkOpts{
+		Name: "bind_test",
+		Code: `
+local p = ...
+assert(typeof(p) == "Player", "typeof")
+p.hp = p.hp - 5
+assert(p:Damage(10) == 85, "Damage")
+assert(p.Pos:Length() == 5, "Length")
+p.Pos.X = 6
+assert(not pcall(function() return p.Secret end), "Secret should not be exposed")
+p.Name = 5
+assert(p.Name == "5", "string coercion")
+local ok, err = pcall(function() return p:Damage(-1) end)
+assert(not ok and string.find(err, "negative damage"), "error return")
+ok, err = pcall(function() return p:Damage("x") end)
+assert(not ok and string.find(err, "bad argument #2 to 'Damage'"), err)
+local tags = p.Tags
+return #tags, p:Scale(p.Pos, 2).X`,
+	}))
+	bindRets, err := bindTest.Call(playerUd.ToValue())
+	if err != nil {
+		panic(fmt.Sprintf("BindType test failed: %v", err))
+	}
+	if player.Health != 85 || player.Pos.X != 6 || player.Name != "5" {
+		panic(fmt.Sprintf("BindType did not modify the Go value: %+v", player))
+	}
+	if n, err := vmutils.FromValue[int](bindRets[0]); err != nil || n != 2 {
+		panic(fmt.Sprintf("Expected 2 tags, got %v (%v)", n, err))
+	}
+	if x, err := vmutils.FromValue[float64](bindRets[1]); err != nil || x != 12 {
+		panic(fmt.Sprintf("Expected scaled X of 12, got %v (%v)", x, err))
+	}
+	fmt.Println("BindType test passed:", player)
+
+	// Cyclic Go values cannot be converted, while values shared without a cycle can
+	cycle := &convNode{Name: "a"}
+	cycle.Next = cycle
+	if _, err := vmutils.ToValue(vm3, cycle); err == nil || !strings.Contains(err.Error(), "cyclic") {
+		panic(fmt.Sprintf("expected cyclic Go value to fail to convert, got %v", err))
+	}
+	cyclicSlice := []any{nil}
+	cyclicSlice[0] = cyclicSlice
+	if _, err := vmutils.ToValue(vm3, cyclicSlice); err == nil || !strings.Contains(err.Error(), "cyclic") {
+		panic(fmt.Sprintf("expected cyclic slice to fail to convert, got %v", err))
+	}
+	shared := &convNode{Name: "shared"}
+	vmutils.Must(vmutils.ToValue(vm3, []*convNode{shared, shared})).Close()
+
+	// Conversion of arguments and fields for every supported kind of Go type
+	probeType := vmutils.Must(vmutils.BindType[convProbe](vmutils.BindOptions{TypeName: "Probe"}))
+	probe := &convProbe{}
+	vmutils.MustOk(vm3.Globals().Set(vmlib.GoString("p"), vmutils.Must(probeType.Create(vm3, probe)).ToValue()))
+	conversionCases := []struct {
+		name    string
+		code    string
+		want    string // The result (as converted by tostring) if the call succeeds
+		wantErr string // A substring of the error if the call fails
+	}{
+		{name: "bool", code: "return p:Not(true)", want: "false"},
+		{name: "int", code: "return p:Inc(41)", want: "42"},
+		{name: "integral float to int", code: "return p:Inc(2.0)", want: "3"},
+		{name: "int8 overflow", code: "return p:Int8(300)", wantErr: "bad argument #2 to 'Int8' (number 300 is out of range for int8)"},
+		{name: "negative uint", code: "return p:Uint(-1)", wantErr: "out of range for uint"},
+		{name: "float32", code: "return p:Double(1.5)", want: "3"},
+		{name: "string", code: "return p:Shout('hi')", want: "hi!"},
+		{name: "number to string", code: "return p:Shout(12)", want: "12!"},
+		{name: "bytes from string", code: "return p:Size('abc')", want: "3"},
+		{name: "bytes from buffer", code: "return p:Size(buffer.fromstring('hi'))", want: "2"},
+		{name: "slice", code: "return p:Sum({1, 2, 3})", want: "6"},
+		{name: "array", code: "return p:Join({'a', 'b'})", want: "a-b"},
+		{name: "array too long", code: "return p:Join({'a', 'b', 'c'})", wantErr: "table has more than 2 elements"},
+		{name: "map", code: "return p:Total({a = 1, b = 2})", want: "3"},
+		{name: "struct", code: "return p:Norm({X = 3, Y = 4})", want: "5"},
+		{name: "struct field", code: "return p:Norm({X = 'x'})", wantErr: "bad argument #2 to 'Norm' (field 'X': number expected, got string)"},
+		{name: "nil pointer", code: "return p:Deref(nil)", want: "-1"},
+		{name: "pointer", code: "return p:Deref(7)", want: "7"},
+		{name: "any number", code: "return p:Kind(1.5)", want: "float64"},
+		{name: "any sequence", code: "return p:Kind({1, 2})", want: "[]interface {}"},
+		{name: "any table", code: "return p:Kind({a = 1})", want: "map[interface {}]interface {}"},
+		{name: "shared table is not a cycle", code: "local s = {1} return p:Kind({s, s})", want: "[]interface {}"},
+		{name: "function handle", code: "return p:IsFunc(print)", want: "true"},
+		{name: "bound userdata", code: "return p:Kind(p)", want: "*main.convProbe"},
+		{name: "wrong type", code: "return p:Sum('x')", wantErr: "bad argument #2 to 'Sum' (table expected, got string)"},
+		{name: "wrong element type", code: "return p:Sum({1, 'x'})", wantErr: "bad argument #2 to 'Sum' (number expected, got string)"},
+		{name: "non-integral int", code: "return p:Inc(1.5)", wantErr: "bad argument #2 to 'Inc' (integer expected, got number)"},
+		{name: "missing argument", code: "return p:Inc()", wantErr: "bad argument #2 to 'Inc' (number expected, got no value)"},
+		{name: "second argument", code: "return p:Repeat('x', 'y')", wantErr: "bad argument #3 to 'Repeat' (number expected, got string)"},
+		{name: "cyclic table as any", code: "local t = {} t.self = t return p:Kind(t)", wantErr: "non-cyclic table expected, got cyclic table"},
+		{name: "cyclic sequence", code: "local t = {} t[1] = t return p:Len(t)", wantErr: "cyclic table"},
+		{name: "cyclic map", code: "local t = {} t.x = {t} return p:Keys(t)", wantErr: "cyclic table"},
+		{name: "unhashable key", code: "return p:Keys({[{}] = true})", wantErr: "hashable key expected, got table"},
+		{name: "embedded field", code: "p.ID = 9 return p.ID", want: "9"},
+		{name: "pointer field", code: "p.Limit = 3 return p.Limit", want: "3"},
+		{name: "nil pointer field", code: "p.Limit = nil return p.Limit", want: "nil"},
+	}
+	for _, tc := range conversionCases {
+		fn := vmutils.Must(vm3.LoadChunk(vmlib.ChunkOpts{
+			Name: "conversion_" + tc.name,
+			Code: "return (function() " + tc.code + " end)()",
+		}))
+		res, err := fn.Call()
+		fn.Close()
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				panic(fmt.Sprintf("conversion case %q: expected error containing %q, got %v", tc.name, tc.wantErr, err))
+			}
+			continue
+		}
+		if err != nil {
+			panic(fmt.Sprintf("conversion case %q failed: %v", tc.name, err))
+		}
+		got := "nil"
+		if len(res) > 0 {
+			switch v := res[0].(type) {
+			case *vmlib.ValueNil:
+			case *vmlib.ValueBoolean:
+				got = fmt.Sprint(v.Value())
+			default:
+				got = vmutils.Must(vmutils.FromValue[string](v))
+			}
+		}
+		for _, r := range res {
+			r.Close()
+		}
+		if got != tc.want {
+			panic(fmt.Sprintf("conversion case %q: expected %q, got %q", tc.name, tc.want, got))
+		}
+	}
+	if probe.ID != 9 || probe.Limit != nil {
+		panic(fmt.Sprintf("Unexpected probe after conversion cases: %+v", probe))
+	}
+	fmt.Println("Conversion test passed")
+
 	vm3.Close()
 
+	// Bindings can be shared between VMs
+	for i := 0; i < 2; i++ {
+		poolVm := vmutils.Must(vmlib.CreateLuaVm())
+		ud := vmutils.Must(playerType.Create(poolVm, &bindPlayer{Name: "pooled"}))
+		if err := poolVm.Globals().Set(vmlib.GoString("p"), ud.ToValue()); err != nil {
+			panic(err)
+		}
+		shared := vmutils.Must(poolVm.LoadChunk(vmlib.ChunkOpts{
+			Name: "shared_binding",
+			Code: `assert(typeof(p) == "Player" and p.Name == "pooled")`,
+		}))
+		if _, err := shared.Call(); err != nil {
+			panic(fmt.Sprintf("Shared binding test failed in VM %d: %v", i, err))
+		}
+		poolVm.Close()
+	}
+	fmt.Println("Shared binding test passed")
+
+	// Userdata inheritance
+	entityTud := vmutils.NewTypedUserData[Entity]()
+	entityTud.SetTypeName("Entity")
+	entityTud.AddFieldGetter("id", func(e *Entity) (vmlib.Value, error) {
+		return vmlib.NewValueInteger(int64(e.ID)), nil
+	})
+	entityTud.AddMethod("describe", func(e *Entity, _ *vmlib.CallbackLua, _ []vmlib.Value) ([]vmlib.Value, error) {
+		return []vmlib.Value{vmlib.GoString(fmt.Sprintf("entity %d", e.ID))}, nil
+	})
+	vmutils.MustOk(entityTud.AddBinaryOp("__add", func(a, b *Entity) (*Entity, error) {
+		return &Entity{ID: a.ID + b.ID}, nil
+	}))
+	npcTud := vmutils.NewTypedUserData[NPC]()
+	npcTud.SetTypeName("NPC")
+	npcTud.AddMethod("talk", func(n *NPC, _ *vmlib.CallbackLua, _ []vmlib.Value) ([]vmlib.Value, error) {
+		return []vmlib.Value{vmlib.GoString(n.Name + " says hi")}, nil
+	})
+	if err := vmutils.SetParent(npcTud, entityTud, nil); err != nil {
+		panic(err)
+	}
+
+	inheritVm := vmutils.Must(vmlib.CreateLuaVm())
+	npcUd := vmutils.Must(npcTud.Create(inheritVm, &NPC{Entity: Entity{ID: 7}, Name: "bob"}))
+	getId := vmutils.Must(inheritVm.CreateFunction(func(funcVm *vmlib.CallbackLua, args []vmlib.Value) ([]vmlib.Value, error) {
+		self, _, err := vmutils.ParseSelf[Identified]("Identified", args)
+		if err != nil {
+			return nil, err
+		}
+		return []vmlib.Value{vmlib.NewValueInteger(int64((*self).GetID()))}, nil
+	}))
+	inheritTest := vmutils.Must(inheritVm.LoadChunk(vmlib.ChunkOpts{
+		Name: "inherit_test",
+		Code: `
+local npc, getId = ...
+assert(typeof(npc) == "NPC", "typeof should report the derived type")
+assert(npc.id == 7, "inherited getter")
+assert(npc:describe() == "entity 7", "inherited method")
+assert(npc:talk() == "bob says hi", "own method")
+assert(getId(npc) == 7, "ParseSelf with interface")
+local sum = npc + npc
+assert(typeof(sum) == "Entity" and sum.id == 14, "inherited operator")`,
+	}))
+	if _, err := inheritTest.Call(npcUd.ToValue(), getId.ToValue()); err != nil {
+		panic(fmt.Sprintf("Inheritance test failed: %v", err))
+	}
+	inheritVm.Close()
+	fmt.Println("Inheritance test passed")
+
+	// Userdata collection hooks
+	collected := 0
+	collectTud := vmutils.NewTypedUserData[Entity]()
+	collectTud.SetTypeName("Collectable")
+	collectTud.OnCollect(func(e *Entity) {
+		collected += e.ID
+	})
+	collectVm := vmutils.Must(vmlib.CreateLuaVm())
+	collectUd := vmutils.Must(collectTud.Create(collectVm, &Entity{ID: 1}))
+	collectUd.Close()
+	if err := collectVm.GCCollect(); err != nil {
+		panic(err)
+	}
+	if collected != 1 {
+		panic(fmt.Sprintf("Expected OnCollect to be called after a full GC cycle, got %d", collected))
+	}
+	// The collect hooks of a child and its parent are both called
+	collectNpcTud := vmutils.NewTypedUserData[NPC]()
+	collectNpcTud.SetTypeName("CollectableNPC")
+	collectNpcTud.OnCollect(func(n *NPC) {
+		collected += 100
+	})
+	vmutils.MustOk(vmutils.SetParent(collectNpcTud, collectTud, nil))
+	vmutils.Must(collectNpcTud.Create(collectVm, &NPC{Entity: Entity{ID: 2}})).Close()
+	vmutils.MustOk(collectVm.GCCollect())
+	if collected != 103 {
+		panic(fmt.Sprintf("Expected the collect hooks of the child and parent to be called, got %d", collected))
+	}
+	closer := &trackingCloser{}
+	closerMt := vmutils.Must(collectVm.CreateTable())
+	vmutils.Must(collectVm.CreateUserDataOpts(closer, closerMt, vmlib.UserDataOpts{CloseOnCollect: true}))
+	vmutils.Must(collectTud.Create(collectVm, &Entity{ID: 10}))
+	collectVm.Close()
+	if collected != 113 || !closer.closed {
+		panic(fmt.Sprintf("Expected userdata to be collected on VM close (collected=%d, closed=%v)", collected, closer.closed))
+	}
+	fmt.Println("Userdata collection hooks test passed")
+
+	// Typed operators
+	vec2Tud := vmutils.NewTypedUserData[Vec2]()
+	vec2Tud.SetTypeName("Vec2")
+	vec2Tud.AddFieldGetter("x", func(v *Vec2) (vmlib.Value, error) { return vmlib.NewValueNumber(v.X), nil })
+	vmutils.MustOk(vec2Tud.AddBinaryOp("__add", func(a, b *Vec2) (*Vec2, error) {
+		return &Vec2{X: a.X + b.X, Y: a.Y + b.Y}, nil
+	}))
+	vmutils.MustOk(vec2Tud.AddNumberOp("__mul", func(v *Vec2, n float64, _ bool) (*Vec2, error) {
+		return &Vec2{X: v.X * n, Y: v.Y * n}, nil
+	}))
+	vmutils.MustOk(vec2Tud.AddNumberOp("__sub", func(v *Vec2, n float64, swapped bool) (*Vec2, error) {
+		if swapped {
+			return &Vec2{X: n - v.X, Y: n - v.Y}, nil
+		}
+		return &Vec2{X: v.X - n, Y: v.Y - n}, nil
+	}))
+	vmutils.MustOk(vec2Tud.AddComparison("__eq", func(a, b *Vec2) (bool, error) { return *a == *b, nil }))
+	vmutils.MustOk(vec2Tud.AddComparison("__lt", func(a, b *Vec2) (bool, error) { return a.X < b.X, nil }))
+	vmutils.MustOk(vec2Tud.AddUnaryOp("__unm", func(v *Vec2) (*Vec2, error) { return &Vec2{X: -v.X, Y: -v.Y}, nil }))
+	vec2Tud.SetLen(func(v *Vec2) (int, error) { return 2, nil })
+	vmutils.MustOk(vec2Tud.AddOperator("__concat", func(funcVm *vmlib.CallbackLua, a, b vmutils.Operand[Vec2]) ([]vmlib.Value, error) {
+		if a.Self != nil {
+			return []vmlib.Value{vmlib.GoString(a.Self.String() + "..")}, nil
+		}
+		return []vmlib.Value{vmlib.GoString(".." + b.Self.String())}, nil
+	}))
+	for _, op := range []string{"__call", "__index", "__newindex", "__unm"} {
+		if err := vec2Tud.AddOperator(op, func(*vmlib.CallbackLua, vmutils.Operand[Vec2], vmutils.Operand[Vec2]) ([]vmlib.Value, error) {
+			return nil, nil
+		}); err == nil {
+			panic("AddOperator should reject " + op)
+		}
+	}
+
+	opVm := vmutils.Must(vmlib.CreateLuaVm())
+	newVec2 := vmutils.Must(opVm.CreateFunction(func(funcVm *vmlib.CallbackLua, args []vmlib.Value) ([]vmlib.Value, error) {
+		x := vmutils.Must(vmutils.FromValue[float64](args[0]))
+		y := vmutils.Must(vmutils.FromValue[float64](args[1]))
+		ud, err := vec2Tud.Create(funcVm.MainState(), &Vec2{X: x, Y: y})
+		if err != nil {
+			return nil, err
+		}
+		return []vmlib.Value{ud.ToValue()}, nil
+	}))
+	opTest := vmutils.Must(opVm.LoadChunk(vmlib.ChunkOpts{
+		Name: "operator_test",
+		Code: `
+local Vec2 = ...
+local a, b = Vec2(1, 2), Vec2(3, 4)
+assert((a + b).x == 4, "__add")
+assert((a * 2).x == 2 and (2 * a).x == 2, "__mul in both orders")
+assert((a - 1).x == 0 and (1 - b).x == -2, "__sub respects operand order")
+assert(a == Vec2(1, 2) and a ~= b, "__eq")
+assert(a < b and not (b < a), "__lt")
+assert((-a).x == -1, "__unm")
+assert(#a == 2, "__len")
+assert(tostring(a) == "(1, 2)", "builtin __tostring")
+assert(a .. "x" == "(1, 2)..", "__concat")
+assert("x" .. a == "..(1, 2)", "__concat swapped")
+local ok, err = pcall(function() return a + "x" end)
+assert(not ok and string.find(err, "attempt to perform arithmetic %(add%) on Vec2 and string"), err)`,
+	}))
+	if _, err := opTest.Call(newVec2.ToValue()); err != nil {
+		panic(fmt.Sprintf("Operator test failed: %v", err))
+	}
+	opVm.Close()
+	fmt.Println("Operator test passed")
+
+	// Typed function wrappers
+	wrapVm := vmutils.Must(vmlib.CreateLuaVm())
+	appendFn := vmutils.Must(vmutils.Func2(wrapVm, appendNumber))
+	greetFn := vmutils.Must(vmutils.WrapFunc(wrapVm, greet))
+	sumFn := vmutils.Must(vmutils.WrapNamedFunc(wrapVm, "sum", func(label string, nums ...int) (string, int) {
+		total := 0
+		for _, n := range nums {
+			total += n
+		}
+		return label, total
+	}))
+	repFn := vmutils.Must(vmutils.Func2(wrapVm, func(s string, n int) (string, error) {
+		return strings.Repeat(s, n), nil
+	}, vmutils.WithName("rep")))
+	cycleFn := vmutils.Must(vmutils.Func0(wrapVm, func() (*convNode, error) {
+		n := &convNode{Name: "loop"}
+		n.Next = n
+		return n, nil
+	}, vmutils.WithName("loop")))
+	wrapTest := vmutils.Must(wrapVm.LoadChunk(vmlib.ChunkOpts{
+		Name: "wrap_test",
+		Code: `
+local appendNumber, greet, sum, rep, loop = ...
+assert(appendNumber("a", 1) == "a1", "Func2")
+local ok, err = pcall(appendNumber, "a", "b")
+assert(not ok and string.find(err, "bad argument #2 to 'appendNumber' %(number expected, got string%)"), err)
+ok, err = pcall(appendNumber, "a")
+assert(not ok and string.find(err, "number expected, got no value"), err)
+assert(greet("bob") == "hello bob", "optional parameter")
+assert(greet("bob", "hi") == "hi bob", "optional parameter set")
+local label, total = sum("total", 1, 2, 3)
+assert(label == "total" and total == 6, "variadic function with multiple returns")
+ok, err = pcall(sum, "total", 1, "x")
+assert(not ok and string.find(err, "bad argument #3 to 'sum'"), err)
+assert(rep("ab", 2) == "abab", "named closure")
+ok, err = pcall(rep, "ab", 1.5)
+assert(not ok and string.find(err, "bad argument #2 to 'rep' %(integer expected, got number%)"), err)
+ok, err = pcall(loop)
+assert(not ok and string.find(err, "bad result of 'loop'"), err)`,
+	}))
+	if _, err := wrapTest.Call(appendFn.ToValue(), greetFn.ToValue(), sumFn.ToValue(), repFn.ToValue(), cycleFn.ToValue()); err != nil {
+		panic(fmt.Sprintf("Function wrapper test failed: %v", err))
+	}
+	wrapVm.Close()
+	fmt.Println("Function wrapper test passed")
+
 	fmt.Println("testing require")
 
 	// Require API
@@ -743,22 +1270,25 @@ func main() {
 	fmt.Println("Lua VM created successfully for require test", vm4)
 
 	mapFs := NewMapFs(map[string]string{
-		"init.luau":               "",
-		"test.luau":               "return require('./foo/test')",
-		"foo/test.luau":           "return require('./test2')",
-		"foo/test2.luau":          "return require('./doo/test2')",
-		"foo/doo/test2.luau":      "return require('@dir-alias/bar')",
-		"foo/dir-alias/bar.luau":  "return require('./baz')",
-		"foo/dir-alias/baz.luau":  "return require('@dir-alias/bat')",
-		"foo/dir-alias/bat.luau":  "return require('./baz2')",
-		"foo/dir-alias/baz2.luau": "return require('../commacomma')",
-		"foo/commacomma.luau":     "return require('./commacomma2')",
-		"foo/commacomma2.luau":    "return require('../roothelper')",
-		"roothelper.luau":         "return require('./roothelper2')",
-		"roothelper2.luau":        "return require('@dir-alias-2/baz')",
-		"dogs/2/baz.luau":         "return require('../../nextluaurcarea/baz')",
-		"nextluaurcarea/baz.luau": "return require('@dir-alias-2/chainy')",
-		"dogs/3/chainy.luau":      "return 3",
+		"init.luau":                "",
+		"test.luau":                "return require('./foo/test')",
+		"foo/test.luau":            "return require('./test2')",
+		"foo/test2.luau":           "return require('./doo/test2')",
+		"foo/doo/test2.luau":       "return require('@dir-alias/bar')",
+		"foo/dir-alias/bar.luau":   "return require('./baz')",
+		"foo/dir-alias/baz.luau":   "return require('@dir-alias/bat')",
+		"foo/dir-alias/bat.luau":   "return require('./baz2')",
+		"foo/dir-alias/baz2.luau":  "return require('../commacomma')",
+		"foo/commacomma.luau":      "return require('./commacomma2')",
+		"foo/commacomma2.luau":     "return require('../roothelper')",
+		"roothelper.luau":          "return require('./roothelper2')",
+		"roothelper2.luau":         "return require('@dir-alias-2/baz')",
+		"dogs/2/baz.luau":          "return require('../../nextluaurcarea/baz')",
+		"nextluaurcarea/baz.luau":  "return require('@dir-alias-2/chainy')",
+		"dogs/3/chainy.luau":       "return 3",
+		"foo/doo/parentalias.luau": "return require('@dir-alias/bat')",
+		"foo/doo/nativealias.luau": "return require('@std/json').name",
+		"foo/doo/libalias.luau":    "return require('@host/db').VERSION",
 		".luaurc": `{
 			"aliases": {
 				"dir-alias": "./foo/dir-alias",
@@ -800,7 +1330,750 @@ func main() {
 		panic("expected require test to return 3, got " + res[0].String())
 	}
 
+	// Libraries
+	dbLib := &vmutils.Library{
+		Name: "db",
+		Functions: map[string]any{
+			"query": func(q string, limit *int) string {
+				if limit != nil {
+					return fmt.Sprintf("%s limit %d", q, *limit)
+				}
+				return q
+			},
+		},
+		Constants: map[string]any{"VERSION": 2},
+		Tables: map[string]*vmutils.Library{
+			"sql": {Functions: map[string]any{"quote": func(s string) string { return "'" + s + "'" }}},
+		},
+	}
+	logLib := &vmutils.Library{
+		Name: "hostlog",
+		Lazy: true,
+		Functions: map[string]any{
+			"info": func(funcVm *vmlib.CallbackLua, args []vmlib.Value) ([]vmlib.Value, error) {
+				return []vmlib.Value{vmlib.GoString("logged")}, nil
+			},
+		},
+	}
+	vmutils.MustOk(simpleRequirer.RegisterNativeModule("@std/json", func(cb *vmlib.CallbackLua) (*vmlib.LuaFunction, error) {
+		return vmutils.Func0(cb.MainState(), func() (map[string]string, error) {
+			return map[string]string{"name": "json"}, nil
+		})
+	}))
+	libRequirer := vmutils.NewLibraryRequirer(simpleRequirer)
+	vmutils.MustOk(libRequirer.AddLibrary("host", dbLib))
+	libRequire := vmutils.Must(vm4.CreateRequireFunction(libRequirer))
+	vmutils.MustOk(vm4.Globals().Set(vmlib.GoString("require"), libRequire.ToValue()))
+	vmutils.MustOk(logLib.Install(vm4, vm4.Globals()))
+	libTest := vmutils.Must(vm4.LoadChunk(vmlib.ChunkOpts{
+		Name: "/",
+		Code: `
+local db = require("@host/db")
+assert(db.query("select") == "select", "library function")
+assert(db.query("select", 5) == "select limit 5", "library function with optional argument")
+assert(db.VERSION == 2, "library constant")
+assert(db.sql.quote("x") == "'x'", "library sub-table")
+assert(require("@host/db/sql").quote("y") == "'y'", "requiring a library sub-table")
+assert(require("@host/db") == db, "libraries should be cached by require")
+assert(not pcall(function() db.VERSION = 3 end), "libraries should be read-only")
+assert(require("@self/test") == 3, "other requires should still work")
+assert(require("@std/json").name == "json", "native module")
+assert(require("@std/json") == require("@std/json"), "native modules should be cached by require")
+assert(not pcall(require, "@std/yaml"), "unknown native modules should not be found")
+assert(require("./foo/doo/parentalias") == 3, "aliases of parent configs should be reachable through a LibraryRequirer with native modules")
+assert(require("./foo/doo/nativealias") == "json", "native aliases should be reachable from subdirectories")
+assert(require("./foo/doo/libalias") == 2, "library aliases should be reachable from subdirectories")
+assert(hostlog.info() == "logged", "lazy library")`,
+	}))
+	if _, err := libTest.Call(); err != nil {
+		panic(fmt.Sprintf("Library test failed: %v", err))
+	}
+	fmt.Println("Library test passed")
+
+	// Libraries holding Luau function handles can be built more than once
+	handleLib := &vmutils.Library{
+		Functions: map[string]any{
+			"double": vmutils.Must(vmutils.Func1(vm4, func(n int) (int, error) { return n * 2, nil })),
+		},
+	}
+	for i := 0; i < 2; i++ {
+		built := vmutils.Must(handleLib.Build(vm4))
+		double := vmutils.Must(built.Get(vmlib.GoString("double")))
+		res := vmutils.Must(double.(*vmlib.ValueFunction).Value().Call(vmlib.NewValueInteger(21)))
+		if n := vmutils.Must(vmutils.FromValue[int](res[0])); n != 42 {
+			panic(fmt.Sprintf("Expected built library function to return 42, got %d", n))
+		}
+		built.Close()
+	}
+
 	vm4.Close() // Ensure we close the VM when done
+
+	// Zip and overlay Vfs's
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	for name, contents := range map[string]string{
+		"plugin/main.luau":   "return require('./util') .. require('../shared')",
+		"plugin/util.luau":   "return 'zip util, '",
+		"shared.luau":        "return 'zip shared'",
+		"plugin/.luaurc":     `{"aliases": {"lib": "./"}}`,
+		"plugin/aliased.lua": "return require('@lib/util')",
+	} {
+		w := vmutils.Must(zw.Create(name))
+		vmutils.Must(w.Write([]byte(contents)))
+	}
+	vmutils.MustOk(zw.Close())
+	zipVfs := vmutils.Must(require.NewZipVfs(bytes.NewReader(zipBuf.Bytes()), int64(zipBuf.Len())))
+	userVfs := require.NewFSVfs(NewMapFs(map[string]string{
+		"main.luau":   "",
+		"shared.luau": "return 'user shared'",
+	}))
+	overlayVfs := vmutils.Must(require.NewOverlayVfs(userVfs, zipVfs))
+
+	vm5 := vmutils.Must(vmlib.CreateLuaVm())
+	overlayRequire := vmutils.Must(vm5.CreateRequireFunction(require.NewSimpleRequirer("overlay", vm5.Globals(), overlayVfs, false)))
+	vmutils.MustOk(vm5.Globals().Set(vmlib.GoString("require"), overlayRequire.ToValue()))
+	overlayTest := vmutils.Must(vm5.LoadChunk(vmlib.ChunkOpts{
+		Name: "/main.luau",
+		Code: `
+assert(require("./plugin/main") == "zip util, user shared", "user layer should take precedence")
+assert(require("./plugin/aliased") == "zip util, ", "aliases inside of a zip archive")`,
+	}))
+	if _, err := overlayTest.Call(); err != nil {
+		panic(fmt.Sprintf("Overlay Vfs test failed: %v", err))
+	}
+	vm5.Close()
+	fmt.Println("Overlay Vfs test passed")
+
+	// In-memory Vfs
+	memVfsCases := []struct {
+		name    string
+		files   map[string]string
+		code    string
+		wantErr string // Substring of the expected error, if any
+	}{
+		{
+			name:  "init.luau",
+			files: map[string]string{"main.luau": "", "pkg/init.luau": "return require('./pkg/value')", "pkg/value.luau": "return 1"},
+			code:  "assert(require('./pkg') == 1)",
+		},
+		{
+			name:    "ambiguity",
+			files:   map[string]string{"main.luau": "", "foo.lua": "return 1", "foo.luau": "return 2"},
+			code:    "return require('./foo')",
+			wantErr: "ambiguous",
+		},
+		{
+			name:  "parent navigation",
+			files: map[string]string{"main.luau": "", "a/b/c.luau": "return require('../../d')", "d.luau": "return 'd'"},
+			code:  "assert(require('./a/b/c') == 'd')",
+		},
+		{
+			name:  ".luaurc aliases",
+			files: map[string]string{"main.luau": "", ".luaurc": `{"aliases": {"lib": "./libs"}}`, "libs/x.luau": "return 'x'"},
+			code:  "assert(require('@lib/x') == 'x')",
+		},
+		{
+			name:    "missing module",
+			files:   map[string]string{"main.luau": ""},
+			code:    "return require('./missing')",
+			wantErr: "missing",
+		},
+	}
+	for _, tc := range memVfsCases {
+		memVm := vmutils.Must(vmlib.CreateLuaVm())
+		memRequire := vmutils.Must(memVm.CreateRequireFunction(require.NewSimpleRequirer("mem", memVm.Globals(), require.NewMemVfs(tc.files), false)))
+		vmutils.MustOk(memVm.Globals().Set(vmlib.GoString("require"), memRequire.ToValue()))
+		_, err := vmutils.Must(memVm.LoadChunk(vmlib.ChunkOpts{Name: "/main.luau", Code: tc.code})).Call()
+		if tc.wantErr == "" && err != nil {
+			panic(fmt.Sprintf("MemVfs case %q failed: %v", tc.name, err))
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			panic(fmt.Sprintf("MemVfs case %q: expected error containing %q, got %v", tc.name, tc.wantErr, err))
+		}
+		memVm.Close()
+	}
+
+	// Panics in requirer callbacks that cannot fail are returned as the error of the require
+	panicVm := vmutils.Must(vmlib.CreateLuaVm())
+	panicRequire := vmutils.Must(panicVm.CreateRequireFunction(panickyRequirer{require.NewSimpleRequirer("panic", panicVm.Globals(), require.NewMemVfs(map[string]string{"x.luau": "return 1"}), false)}))
+	vmutils.MustOk(panicVm.Globals().Set(vmlib.GoString("require"), panicRequire.ToValue()))
+	for i := 0; i < 2; i++ {
+		if _, err := vmutils.Must(panicVm.LoadChunk(vmlib.ChunkOpts{Name: "/main.luau", Code: "return require('./x')"})).Call(); err == nil || !strings.Contains(err.Error(), "panic in HasModule: no module today") {
+			panic(fmt.Sprintf("expected the HasModule panic to be returned, got %v", err))
+		}
+	}
+	panicVm.Close()
+
+	memVfs := require.NewMemVfs(map[string]string{"a/x.luau": "return 1"})
+	var memEvents []string
+	stopListening := memVfs.OnChange(func(e require.MemVfsEvent) {
+		memEvents = append(memEvents, e.Op.String()+" "+e.OldPath+" "+e.Path)
+	})
+	vmutils.MustOk(memVfs.WriteFile("/a/y.luau", []byte("return 2")))
+	vmutils.MustOk(memVfs.Rename("/a", "/b"))
+	vmutils.MustOk(memVfs.Remove("/b/x.luau"))
+	if err := memVfs.WriteFile("/b/y.luau/z.luau", nil); err == nil {
+		panic("expected writing below a file to fail")
+	}
+	stopListening()
+	vmutils.MustOk(memVfs.Remove("/b"))
+	wantEvents := []string{"write  /a/y.luau", "rename /a/x.luau /b/x.luau", "rename /a/y.luau /b/y.luau", "remove  /b/x.luau"}
+	if strings.Join(memEvents, "|") != strings.Join(wantEvents, "|") {
+		panic(fmt.Sprintf("unexpected MemVfs events: %q", memEvents))
+	}
+	if entries, err := memVfs.ReadDir("/"); err != nil || len(entries) != 0 {
+		panic(fmt.Sprintf("expected MemVfs to be empty, got %v (%v)", entries, err))
+	}
+	fmt.Println("MemVfs test passed")
+
+	// Require cache control and hot reloading
+	reloadVfs := require.NewMemVfs(map[string]string{
+		"main.luau": "",
+		"a.luau":    "return 'a' .. require('./b')",
+		"b.luau":    "return 'b1'",
+		"c.luau":    "return {}",
+	})
+	reloadVm := vmutils.Must(vmlib.CreateLuaVm())
+	reloadRequirer := require.NewSimpleRequirer("reload", reloadVm.Globals(), reloadVfs, false)
+	reloadRequire := vmutils.Must(reloadVm.CreateRequireFunction(reloadRequirer))
+	vmutils.MustOk(reloadVm.Globals().Set(vmlib.GoString("require"), reloadRequire.ToValue()))
+	var reloadEvicted []string
+	watcher := reloadRequirer.Watch(reloadVm, require.WatchOptions{OnEvict: func(keys []string) {
+		reloadEvicted = append(reloadEvicted, keys...)
+	}})
+	runReload := func(code string) {
+		if _, err := vmutils.Must(reloadVm.LoadChunk(vmlib.ChunkOpts{Name: "/main.luau", Code: code})).Call(); err != nil {
+			panic(fmt.Sprintf("Hot reload test failed: %v", err))
+		}
+	}
+	runReload(`
+assert(require("./a") == "ab1")
+_G.c = require("./c")`)
+	cache := reloadVm.RequireCache()
+	wantKeys := []string{"reload@/a.luau", "reload@/b.luau", "reload@/c.luau"}
+	if keys := vmutils.Must(cache.Keys()); strings.Join(keys, ",") != strings.Join(wantKeys, ",") {
+		panic(fmt.Sprintf("unexpected require cache keys: %q", keys))
+	}
+	if dependents := reloadRequirer.Dependents("reload@/b.luau"); len(dependents) != 1 || dependents[0] != "reload@/a.luau" {
+		panic(fmt.Sprintf("unexpected dependents of b: %q", dependents))
+	}
+	// The requirer's graph is what invalidation follows
+	if affected := strings.Join(reloadRequirer.Graph().TransitiveDependents("reload@/b.luau"), ","); !strings.Contains(affected, "reload@/a.luau,reload@/b.luau") || strings.Contains(affected, "reload@/c.luau") {
+		panic(fmt.Sprintf("unexpected modules affected by b: %q", affected))
+	}
+	vmutils.MustOk(reloadVfs.WriteFile("/b.luau", []byte("return 'b2'")))
+	runReload(`
+assert(require("./a") == "ab2", "dependents of changed modules should be reloaded")
+assert(require("./c") == _G.c, "unrelated modules should stay cached")`)
+	if strings.Join(reloadEvicted, ",") != "reload@/a.luau,reload@/b.luau" {
+		panic(fmt.Sprintf("unexpected evicted modules: %q", reloadEvicted))
+	}
+	watcher.Stop()
+	vmutils.MustOk(reloadVfs.WriteFile("/b.luau", []byte("return 'b3'")))
+	runReload(`assert(require("./a") == "ab2", "stopped watchers should not evict modules")`)
+	if evicted := vmutils.Must(cache.EvictPrefix("reload@/c")); len(evicted) != 1 {
+		panic(fmt.Sprintf("expected c to be evicted, got %q", evicted))
+	}
+	runReload(`assert(require("./c") ~= _G.c, "evicted modules should be reloaded")`)
+	vmutils.MustOk(cache.Clear())
+	if keys := vmutils.Must(cache.Keys()); len(keys) != 0 {
+		panic(fmt.Sprintf("expected an empty require cache, got %q", keys))
+	}
+	runReload(`assert(require("./a") == "ab3")`)
+	reloadVm.Close()
+
+	// Hot reloading from a real directory, including .luaurc changes and deleted files
+	watchDir := vmutils.Must(os.MkdirTemp("", "gluau-watch"))
+	defer os.RemoveAll(watchDir)
+	writeWatched := func(name, content string) {
+		vmutils.MustOk(os.WriteFile(filepath.Join(watchDir, name), []byte(content), 0o644))
+	}
+	writeWatched("a.luau", "return 'a' .. require('@lib/b')")
+	writeWatched("b.luau", "return 'b1'")
+	writeWatched("c.luau", "return 'c'")
+	writeWatched(".luaurc", `{"aliases": {"lib": "./"}}`)
+	watchVm := vmutils.Must(vmlib.CreateLuaVm())
+	watchRequirer := require.NewSimpleRequirer("watch", watchVm.Globals(), vmutils.Must(require.NewDirVfs(watchDir)), false)
+	watchRequire := vmutils.Must(watchVm.CreateRequireFunction(watchRequirer))
+	vmutils.MustOk(watchVm.Globals().Set(vmlib.GoString("require"), watchRequire.ToValue()))
+	dirWatcher := watchRequirer.Watch(watchVm, require.WatchOptions{PollInterval: 10 * time.Millisecond})
+	runWatched := func(code string) {
+		if _, err := vmutils.Must(watchVm.LoadChunk(vmlib.ChunkOpts{Name: "/main.luau", Code: code})).Call(); err != nil {
+			panic(fmt.Sprintf("Directory hot reload test failed: %v", err))
+		}
+	}
+	waitPending := func(want string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(strings.Join(dirWatcher.Pending(), ","), want) {
+			if time.Now().After(deadline) {
+				panic(fmt.Sprintf("expected %s to be reported as changed, got %q", want, dirWatcher.Pending()))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	runWatched(`assert(require("./a") == "ab1"); assert(require("./c") == "c")`)
+	writeWatched("b.luau", "return 'b2'")
+	waitPending("/b.luau")
+	runWatched(`assert(require("./a") == "ab2", "changed files in directories should be reloaded")`)
+	writeWatched(".luaurc", `{"aliases": {"lib": "./"}, "languageMode": "nonstrict"}`)
+	waitPending("/.luaurc")
+	if evicted := vmutils.Must(dirWatcher.Flush()); len(evicted) != 3 {
+		panic(fmt.Sprintf("expected .luaurc changes to evict all modules, got %q", evicted))
+	}
+	runWatched(`assert(require("./c") == "c")`)
+	vmutils.MustOk(os.Remove(filepath.Join(watchDir, "c.luau")))
+	waitPending("/c.luau")
+	vmutils.Must(dirWatcher.Flush())
+	time.Sleep(50 * time.Millisecond)
+	if pending := dirWatcher.Pending(); len(pending) != 0 {
+		panic(fmt.Sprintf("deleted modules should not be reported as changed again, got %q", pending))
+	}
+	runWatched(`assert(not pcall(require, "./c"), "deleted modules should not be found")`)
+	dirWatcher.Stop()
+	watchVm.Close()
+	fmt.Println("Hot reload test passed")
+
+	// Dependency graphs
+	graphVm := vmutils.Must(vmlib.CreateLuaVm())
+	graph := require.NewDependencyGraph()
+	graphVfs := require.NewMemVfs(map[string]string{
+		"main.luau": "",
+		"a.luau":    "return require('./b') .. require('./c')",
+		"b.luau":    "return 'b'",
+		"c.luau":    "return require('./b')",
+		"x.luau":    "return require('./y')",
+		"y.luau":    "return require('./x')",
+		"self.luau": "return require('./self')",
+	})
+	graphRequirer := require.NewDependencyRecorder(require.NewSimpleRequirer("graph", graphVm.Globals(), graphVfs, false), graph)
+	graphRequire := vmutils.Must(graphVm.CreateRequireFunction(graphRequirer))
+	vmutils.MustOk(graphVm.Globals().Set(vmlib.GoString("require"), graphRequire.ToValue()))
+	vmutils.Must(vmutils.Must(graphVm.LoadChunk(vmlib.ChunkOpts{Name: "/main.luau", Code: `assert(require("./a") == "bb")`})).Call())
+	order := vmutils.Must(graph.TopologicalOrder())
+	if strings.Join(order, ",") != "graph@/b.luau,graph@/c.luau,graph@/a.luau,graph@/main.luau" {
+		panic(fmt.Sprintf("unexpected topological order: %q", order))
+	}
+	if dependents := graph.Dependents("graph@/b.luau"); strings.Join(dependents, ",") != "graph@/a.luau,graph@/c.luau" {
+		panic(fmt.Sprintf("unexpected dependents of b: %q", dependents))
+	}
+	if !strings.Contains(graph.DOT(), `"graph@/a.luau" -> "graph@/c.luau";`) {
+		panic("DOT output is missing an edge:\n" + graph.DOT())
+	}
+	graphJson := string(vmutils.Must(json.Marshal(graph)))
+	if !strings.Contains(graphJson, `{"from":"graph@/c.luau","to":"graph@/b.luau"}`) {
+		panic("JSON output is missing an edge: " + graphJson)
+	}
+	if _, err := vmutils.Must(graphVm.LoadChunk(vmlib.ChunkOpts{Name: "/main.luau", Code: `return require("./x")`})).Call(); err == nil {
+		panic("expected cyclic require to fail")
+	}
+	var cycleErr *require.CycleError
+	if _, err := graph.TopologicalOrder(); !errors.As(err, &cycleErr) || strings.Join(cycleErr.Path, ",") != "graph@/x.luau,graph@/y.luau,graph@/x.luau" {
+		panic(fmt.Sprintf("expected a dependency cycle, got %v", err))
+	}
+	if _, err := vmutils.Must(graphVm.LoadChunk(vmlib.ChunkOpts{Name: "/main.luau", Code: `return require("./self")`})).Call(); err == nil {
+		panic("expected self require to fail")
+	}
+	if dependencies := graph.Dependencies("graph@/self.luau"); strings.Join(dependencies, ",") != "graph@/self.luau" {
+		panic(fmt.Sprintf("expected self requires to be recorded, got %q", dependencies))
+	}
+	graphVm.Close()
+	fmt.Println("Dependency graph test passed")
+
+	// Require policies
+	policyVm := vmutils.Must(vmlib.CreateLuaVm())
+	policyRequirer := require.NewSimpleRequirer("policy", policyVm.Globals(), require.NewMemVfs(map[string]string{
+		"secret.luau":             "return 'secret'",
+		"sandbox/.luaurc":         `{"aliases": {"up": "../", "lib": "./lib"}}`,
+		"sandbox/main.luau":       "",
+		"sandbox/untrusted.luau":  "return require('./lib/ok')",
+		"sandbox/lib/ok.luau":     "return 'ok'",
+		"sandbox/lib/escape.luau": "return require('../../secret')",
+		"sandbox/private/x.luau":  "return 'private'",
+	}), false)
+	policyRequirer.SetPolicy(&require.PolicyRules{
+		Root:            "/sandbox",
+		DeniedModules:   []string{"/sandbox/private"},
+		DeniedAliases:   []string{"up"},
+		DeniedRequirers: []string{"/sandbox/untrusted.luau"},
+	})
+	policyRequire := vmutils.Must(policyVm.CreateRequireFunction(policyRequirer))
+	vmutils.MustOk(policyVm.Globals().Set(vmlib.GoString("require"), policyRequire.ToValue()))
+	_, err = vmutils.Must(policyVm.LoadChunk(vmlib.ChunkOpts{
+		Name: "/sandbox/main.luau",
+		Code: `
+local function denied(path, reason)
+	local ok, err = pcall(require, path)
+	assert(not ok, path .. " should be denied")
+	assert(string.find(tostring(err), "require denied", 1, true), "unexpected error for " .. path .. ": " .. tostring(err))
+	assert(string.find(tostring(err), reason, 1, true), "unexpected error for " .. path .. ": " .. tostring(err))
+end
+assert(require("./lib/ok") == "ok")
+assert(require("@lib/ok") == "ok")
+denied("../secret", "outside of /sandbox")
+denied("./lib/escape", "outside of /sandbox")
+denied("@up/secret", "alias @up may not be used")
+denied("./private/x", "may not be required")
+denied("./untrusted", "may not require modules")`,
+	})).Call()
+	if err != nil {
+		panic(fmt.Sprintf("Require policy test failed: %v", err))
+	}
+	policyVm.Close()
+	fmt.Println("Require policy test passed")
+
+	// .luaurc parsing and require resolution
+	rc := vmutils.Must(require.ParseLuauRC("/.luaurc", []byte(`{
+	// Comments and trailing commas are allowed
+	"languageMode": "strict",
+	"lint": {"*": true, "LocalUnused": false},
+	"globals": ["game"],
+	"aliases": {"Lib": "./libs", "abs": "/shared"},
+}`)))
+	if rc.LanguageMode != "strict" || rc.Lint["LocalUnused"] || len(rc.Globals) != 1 || rc.Aliases["Lib"] != "./libs" {
+		panic(fmt.Sprintf("unexpected parsed .luaurc: %+v", rc))
+	}
+	if p, ok := rc.Alias("@lib"); !ok || p != "./libs" {
+		panic("aliases should be case-insensitive")
+	}
+	_, err = require.ParseLuauRC("/bad/.luaurc", []byte("{\n\t\"languageMode\": \"loose\",\n\t\"aliases\": {\"a/b\": \"./x\"}\n}"))
+	var rcErrs require.LuauRCErrors
+	if !errors.As(err, &rcErrs) || len(rcErrs) != 2 || rcErrs[0].Error() != `/bad/.luaurc:2:18: languageMode must be one of "nocheck", "nonstrict" or "strict"` || rcErrs[1].Line != 3 {
+		panic(fmt.Sprintf("unexpected .luaurc validation errors: %v", err))
+	}
+	var rcErr *require.LuauRCError
+	if _, err := require.ParseLuauRC("/bad/.luaurc", []byte(`{"aliases": {"a": }}`)); !errors.As(err, &rcErr) || rcErr.Column != 19 {
+		panic(fmt.Sprintf("unexpected .luaurc syntax error: %v", err))
+	}
+	// Alias names are validated like Luau does: "self" is shadowed, later definitions replace earlier ones
+	rc = vmutils.Must(require.ParseLuauRC("/.luaurc", []byte(`{"aliases": {"self": "./s", "Dup": "./a", "dup": "./b"}}`)))
+	if p, ok := rc.Alias("@DUP"); !ok || p != "./b" || len(rc.Aliases) != 2 {
+		panic(fmt.Sprintf("unexpected aliases: %v", rc.Aliases))
+	}
+	if _, err := require.ParseLuauRC("/.luaurc", []byte(`{"aliases": {"..": "./x"}}`)); !errors.As(err, &rcErrs) || len(rcErrs) != 1 {
+		panic(fmt.Sprintf("expected .. to be an invalid alias, got %v", err))
+	}
+
+	resolveVfs := require.NewMemVfs(map[string]string{
+		"project/.luaurc":         `{"aliases": {"lib": "./libs"}}`,
+		"project/src/main.luau":   "",
+		"project/libs/util.luau":  "",
+		"project/src/dup.lua":     "",
+		"project/src/dup.luau":    "",
+		"project/src/both.luau":   "",
+		"project/src/both/x.luau": "",
+	})
+	resolution, err := require.Resolve(resolveVfs, "/project/src/main.luau", "@lib/util")
+	if err != nil || resolution.Path != "/project/libs/util.luau" {
+		panic(fmt.Sprintf("unexpected resolution: %v\n%s", err, resolution.Explain()))
+	}
+	if !strings.Contains(resolution.Explain(), `@lib is defined as "./libs" in /project/.luaurc`) {
+		panic("resolution should explain the alias:\n" + resolution.Explain())
+	}
+	if resolution, err := require.Resolve(resolveVfs, "/project/src/main.luau", "../libs/util"); err != nil || resolution.Path != "/project/libs/util.luau" {
+		panic(fmt.Sprintf("unexpected relative resolution: %v", err))
+	}
+	if _, err := require.Resolve(resolveVfs, "/project/src/main.luau", "./dup"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		panic(fmt.Sprintf("expected an ambiguous resolution, got %v", err))
+	}
+	if _, err := require.Resolve(resolveVfs, "/project/src/main.luau", "./both"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		panic(fmt.Sprintf("expected a module with a directory of the same name to be ambiguous, got %v", err))
+	}
+	if _, err := require.Resolve(resolveVfs, "/project/src/main.luau", "@missing/x"); err == nil {
+		panic("expected an unknown alias to fail")
+	}
+	fmt.Println("LuauRC test passed")
+
+	// Bundling
+	bundleVfs := require.NewMemVfs(map[string]string{
+		"app/.luaurc":       `{"aliases": {"lib": "./lib"}}`,
+		"app/main.luau":     "local a = require(\"./lib/a\")\nlocal b = require(\"@lib/b\")\nlocal function lazy(name)\n\treturn require(name)\nend\nreturn a .. b .. tostring(a == require(\"./lib/a\"))",
+		"app/lib/a.luau":    "-- require('./ignored') in a comment\nreturn 'a' .. require './b'",
+		"app/lib/b.luau":    "return 'b'",
+		"app/failing.luau":  "return require('./lib/fail')",
+		"app/lib/fail.luau": "local x = 1\nerror('boom')",
+		"app/retry.luau":    "local ok = pcall(function() return require('./lib/fail') end)\nlocal _, err = pcall(function() return require('./lib/fail') end)\nreturn tostring(err)",
+	})
+	appBundle := vmutils.Must(bundle.Build(bundleVfs, "/app/main.luau", nil))
+	if strings.Join(appBundle.Modules, ",") != "/app/lib/b.luau,/app/lib/a.luau,/app/main.luau" {
+		panic(fmt.Sprintf("unexpected bundled modules: %q", appBundle.Modules))
+	}
+	if len(appBundle.Warnings) != 1 || appBundle.Warnings[0].Line != 4 {
+		panic(fmt.Sprintf("expected a warning about the dynamic require, got %v", appBundle.Warnings))
+	}
+	bundleVm := vmutils.Must(vmlib.CreateLuaVm())
+	bundleResult := vmutils.Must(vmutils.Must(appBundle.Load(bundleVm, nil)).Call())
+	if len(bundleResult) != 1 || bundleResult[0].(*vmlib.ValueString).Value().String() != "abbtrue" {
+		panic(fmt.Sprintf("unexpected bundle result: %v", bundleResult))
+	}
+	bytecode := vmutils.Must(appBundle.Bytecode(bundleVm, nil))
+	bytecodeResult := vmutils.Must(vmutils.Must(bundleVm.LoadChunk(vmlib.ChunkOpts{Name: "bundle", Code: string(bytecode), Mode: vmlib.ChunkModeBinary})).Call())
+	if bytecodeResult[0].(*vmlib.ValueString).Value().String() != "abbtrue" {
+		panic("unexpected bytecode bundle result")
+	}
+	// Position maps the bundle line of a module line back to it in both modes
+	checkBundlePosition := func(b *bundle.Bundle) {
+		for i, l := range strings.Split(b.Source, "\n") {
+			if strings.Contains(l, "error('boom')") {
+				if chunk, line, ok := b.Position(i + 1); !ok || chunk != "/app/lib/fail.luau" || line != 2 {
+					panic(fmt.Sprintf("unexpected position of bundle line %d: %s:%d (%v)", i+1, chunk, line, ok))
+				}
+				return
+			}
+		}
+		panic("failing module not found in bundle")
+	}
+	failBundle := vmutils.Must(bundle.Build(bundleVfs, "/app/failing.luau", nil))
+	checkBundlePosition(failBundle)
+	_, err = vmutils.Must(failBundle.Load(bundleVm, nil)).Call()
+	if err == nil || !strings.Contains(failBundle.TranslateError(err.Error()), "/app/lib/fail.luau:2: boom") {
+		panic(fmt.Sprintf("expected the bundle error to map to the failing module, got %v", err))
+	}
+	failBundle = vmutils.Must(bundle.Build(bundleVfs, "/app/failing.luau", &bundle.Options{PreserveChunkNames: true}))
+	checkBundlePosition(failBundle)
+	_, err = vmutils.Must(failBundle.Load(bundleVm, nil)).Call()
+	if err == nil || !strings.Contains(err.Error(), "/app/lib/fail.luau:2: boom") {
+		panic(fmt.Sprintf("expected the bundle error to keep the chunk name of the failing module, got %v", err))
+	}
+	retryBundle := vmutils.Must(bundle.Build(bundleVfs, "/app/retry.luau", &bundle.Options{PreserveChunkNames: true}))
+	retryResult := vmutils.Must(vmutils.Must(retryBundle.Load(bundleVm, nil)).Call())
+	if msg := vmutils.Must(vmutils.FromValue[string](retryResult[0])); !strings.Contains(msg, "boom") || strings.Contains(msg, "cyclic") {
+		panic(fmt.Sprintf("expected requiring a failed module again to report its error, got %s", msg))
+	}
+	bundleVm.Close()
+	fmt.Println("Bundle test passed")
+
+	// Require-by-string conformance, derived from the Luau require-by-string tests
+	conformanceDir := vmutils.Must(os.MkdirTemp("", "gluau-require"))
+	defer os.RemoveAll(conformanceDir)
+	for name, contents := range map[string]string{
+		"require/without_config/main.luau":                                "",
+		"require/without_config/dependency.luau":                          "return {'result from dependency'}",
+		"require/without_config/module.luau":                              "local result = require('./dependency')\nresult[2] = 'required into module'\nreturn result",
+		"require/without_config/lua_dependency.lua":                       "return 'result from lua_dependency'",
+		"require/without_config/luau/init.luau":                           "return 'result from init.luau'",
+		"require/without_config/lua/init.lua":                             "return 'result from init.lua'",
+		"require/without_config/ambiguous/file/dependency.luau":           "return 1",
+		"require/without_config/ambiguous/file/dependency.lua":            "return 2",
+		"require/without_config/ambiguous/directory/dependency.luau":      "return 1",
+		"require/without_config/ambiguous/directory/dependency/init.luau": "return 2",
+		"require/with_config/.luaurc":                                     `{"aliases": {"dep": "./wrong", "parent": "../without_config"}}`,
+		"require/with_config/src/.luaurc":                                 `{"aliases": {"dep": "./other_dependency"}}`,
+		"require/with_config/src/main.luau":                               "",
+		"require/with_config/src/other_dependency.luau":                   "return 'result from other_dependency'",
+	} {
+		file := filepath.Join(conformanceDir, filepath.FromSlash(name))
+		vmutils.MustOk(os.MkdirAll(filepath.Dir(file), 0o755))
+		vmutils.MustOk(os.WriteFile(file, []byte(contents), 0o644))
+	}
+	conformanceVfs := vmutils.Must(require.NewDirVfs(conformanceDir))
+	conformanceVfs.SetCwd("/require")
+	conformanceCases := []struct {
+		name    string
+		chunk   string
+		code    string
+		want    string
+		wantErr string
+	}{
+		{"simple relative path", "without_config/main.luau", "return require('./dependency')[1]", "result from dependency", ""},
+		{"relative to requiring file", "without_config/main.luau", "return require('./module')[2]", "required into module", ""},
+		{"lua extension", "without_config/main.luau", "return require('./lua_dependency')", "result from lua_dependency", ""},
+		{"init.luau", "without_config/main.luau", "return require('./luau')", "result from init.luau", ""},
+		{"init.lua", "without_config/main.luau", "return require('./lua')", "result from init.lua", ""},
+		{"cached result", "without_config/main.luau", "return tostring(require('./dependency') == require('./dependency'))", "true", ""},
+		{"init files cannot be required directly", "without_config/main.luau", "return require('./luau/init')", "", "init"},
+		{"unprefixed path", "without_config/main.luau", "return require('dependency')", "", "prefix"},
+		{"file ambiguity", "without_config/main.luau", "return require('./ambiguous/file/dependency')", "", "ambiguous"},
+		{"directory ambiguity", "without_config/main.luau", "return require('./ambiguous/directory/dependency')", "", "ambiguous"},
+		{"closest alias wins", "with_config/src/main.luau", "return require('@dep')", "result from other_dependency", ""},
+		{"alias in parent config", "with_config/src/main.luau", "return require('@parent/dependency')[1]", "result from dependency", ""},
+		{"parent navigation", "with_config/src/main.luau", "return require('../../without_config/dependency')[1]", "result from dependency", ""},
+		{"absolute chunk name", "/require/without_config/main.luau", "return require('./dependency')[1]", "result from dependency", ""},
+		{"dot relative chunk name", "./without_config/main.luau", "return require('./dependency')[1]", "result from dependency", ""},
+		{"windows separators", "without_config\\main.luau", "return require('./dependency')[1]", "result from dependency", ""},
+	}
+	for _, tc := range conformanceCases {
+		conformanceVm := vmutils.Must(vmlib.CreateLuaVm())
+		conformanceRequire := vmutils.Must(conformanceVm.CreateRequireFunction(require.NewSimpleRequirer("conformance", conformanceVm.Globals(), conformanceVfs, false)))
+		vmutils.MustOk(conformanceVm.Globals().Set(vmlib.GoString("require"), conformanceRequire.ToValue()))
+		result, err := vmutils.Must(conformanceVm.LoadChunk(vmlib.ChunkOpts{Name: tc.chunk, Code: tc.code})).Call()
+		switch {
+		case tc.wantErr != "":
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				panic(fmt.Sprintf("conformance case %q: expected error containing %q, got %v", tc.name, tc.wantErr, err))
+			}
+		case err != nil:
+			panic(fmt.Sprintf("conformance case %q failed: %v", tc.name, err))
+		case len(result) != 1 || result[0].Type() != vmlib.LuaValueString || result[0].(*vmlib.ValueString).Value().String() != tc.want:
+			panic(fmt.Sprintf("conformance case %q: expected %q, got %v", tc.name, tc.want, result))
+		}
+		conformanceVm.Close()
+	}
+	fmt.Println("Require conformance test passed")
+
+	// Zero-copy string and buffer views
+	viewVm := vmutils.Must(vmlib.CreateLuaVm())
+	viewStr := vmutils.Must(viewVm.CreateString("zero-copy"))
+	vmutils.MustOk(viewStr.WithBytes(func(b []byte) error {
+		if string(b) != "zero-copy" {
+			return fmt.Errorf("unexpected string view %q", b)
+		}
+		if err := viewVm.Close(); !errors.Is(err, vmlib.ErrViewOpen) {
+			return fmt.Errorf("expected closing the VM to fail with ErrViewOpen, got %v", err)
+		}
+		if _, err := viewVm.CreateString("re-entry"); !errors.Is(err, vmlib.ErrViewOpen) {
+			return fmt.Errorf("expected re-entry to fail with ErrViewOpen, got %v", err)
+		}
+		return nil
+	}))
+	viewBuf := vmutils.Must(viewVm.CreateBuffer([]byte{1, 2, 3}))
+	viewWrite := vmutils.Must(viewVm.LoadChunk(vmlib.ChunkOpts{Name: "view", Code: "local b = ...; buffer.writeu8(b, 1, 7)"}))
+	viewTable := vmutils.Must(viewVm.CreateTable())
+	vmutils.MustOk(viewBuf.WithBytes(func(b []byte) error {
+		b[0] = 42
+		if _, err := viewWrite.Call(); !errors.Is(err, vmlib.ErrViewOpen) {
+			return fmt.Errorf("expected calling a function to fail with ErrViewOpen, got %v", err)
+		}
+		if err := viewTable.Set(vmlib.GoString("k"), vmlib.NewValueBoolean(true)); !errors.Is(err, vmlib.ErrViewOpen) {
+			return fmt.Errorf("expected setting a table key to fail with ErrViewOpen, got %v", err)
+		}
+		return nil
+	}))
+	vmutils.Must(viewWrite.Call(viewBuf.ToValue().Clone()))
+	if got := viewBuf.Bytes(); !bytes.Equal(got, []byte{42, 7, 3}) {
+		panic(fmt.Sprintf("writes through a buffer view should change the buffer, got %v", got))
+	}
+	if _, err := viewVm.CreateString("after view"); err != nil {
+		panic(fmt.Sprintf("the VM should be usable after a view is closed: %v", err))
+	}
+	viewVm.Close()
+	fmt.Println("Zero-copy view test passed")
+
+	// Typed buffer accessors shared with the Luau buffer library
+	bufioVm := vmutils.Must(vmlib.CreateLuaVm())
+	bufioChunk := vmutils.Must(bufioVm.LoadChunk(vmlib.ChunkOpts{
+		Name: "bufferio",
+		Code: `
+			local b = buffer.create(16)
+			buffer.writeu32(b, 0, 0xDEADBEEF)
+			buffer.writef64(b, 4, 1.5)
+			return b, function()
+				return buffer.readu16(b, 12), buffer.readstring(b, 14, 2)
+			end
+		`,
+	}))
+	bufioRes := vmutils.Must(bufioChunk.Call())
+	bufioBuf := bufioRes[0].(*vmlib.ValueBuffer).Value()
+	if v := vmutils.Must(bufioBuf.ReadU32(0)); v != 0xDEADBEEF {
+		panic(fmt.Sprintf("expected 0xDEADBEEF, got %#x", v))
+	}
+	if v := vmutils.Must(bufioBuf.ReadU32BE(0)); v != 0xEFBEADDE {
+		panic(fmt.Sprintf("expected big-endian 0xEFBEADDE, got %#x", v))
+	}
+	if v := vmutils.Must(bufioBuf.ReadF64(4)); v != 1.5 {
+		panic(fmt.Sprintf("expected 1.5, got %v", v))
+	}
+	vmutils.MustOk(bufioBuf.WriteU16(12, 513))
+	vmutils.MustOk(bufioBuf.WriteString(14, "ok"))
+	if err := bufioBuf.WriteU32(14, 0); err == nil {
+		panic("expected an out of bounds write to fail")
+	}
+	bufioRead := vmutils.Must(bufioRes[1].(*vmlib.ValueFunction).Value().Call())
+	if v := bufioRead[0].(*vmlib.ValueNumber).Value(); v != 513 {
+		panic(fmt.Sprintf("expected Luau to read 513, got %v", v))
+	}
+	if v := bufioRead[1].(*vmlib.ValueString).Value().String(); v != "ok" {
+		panic(fmt.Sprintf("expected Luau to read \"ok\", got %q", v))
+	}
+	cursor := bufioBuf.Cursor()
+	vmutils.Must(cursor.Seek(-2, io.SeekEnd))
+	if tail := vmutils.Must(io.ReadAll(cursor)); string(tail) != "ok" {
+		panic(fmt.Sprintf("expected cursor to read \"ok\", got %q", tail))
+	}
+	if n, err := cursor.Write([]byte("x")); n != 0 || err != io.ErrShortWrite {
+		panic(fmt.Sprintf("expected a short write at the end of the buffer, got %d, %v", n, err))
+	}
+	bufioVm.Close()
+	fmt.Println("Buffer accessor test passed")
+
+	// Buffer fill, copy and resize
+	resizeVm := vmutils.Must(vmlib.CreateLuaVm())
+	sized := vmutils.Must(resizeVm.CreateBufferSize(8))
+	if got := sized.Bytes(); !bytes.Equal(got, make([]byte, 8)) {
+		panic(fmt.Sprintf("expected a zero-filled buffer, got %v", got))
+	}
+	vmutils.MustOk(sized.Fill(2, 0xAA, 4))
+	if got := sized.Bytes(); !bytes.Equal(got, []byte{0, 0, 0xAA, 0xAA, 0xAA, 0xAA, 0, 0}) {
+		panic(fmt.Sprintf("unexpected buffer after Fill: %v", got))
+	}
+	if err := sized.Fill(6, 0, 4); err == nil {
+		panic("expected an out of bounds fill to fail")
+	}
+	source := vmutils.Must(resizeVm.CreateBuffer([]byte("abcdef")))
+	vmutils.MustOk(sized.CopyFrom(source, 1, 0, 3))
+	vmutils.MustOk(sized.CopyFrom(sized, 0, 5, 3)) // Copying within a buffer
+	if got := sized.Bytes(); !bytes.Equal(got, []byte{'b', 'c', 'd', 0xAA, 0xAA, 'b', 'c', 'd'}) {
+		panic(fmt.Sprintf("unexpected buffer after CopyFrom: %v", got))
+	}
+	grown := vmutils.Must(source.ResizeCopy(8))
+	if got := grown.Bytes(); !bytes.Equal(got, []byte{'a', 'b', 'c', 'd', 'e', 'f', 0, 0}) {
+		panic(fmt.Sprintf("unexpected grown buffer: %v", got))
+	}
+	shrunk := vmutils.Must(source.ResizeCopy(2))
+	if got := string(shrunk.Bytes()); got != "ab" {
+		panic(fmt.Sprintf("unexpected shrunk buffer: %q", got))
+	}
+	resizeVm.Close()
+	fmt.Println("Buffer resize test passed")
+
+	// Streaming between Go readers/writers and Luau
+	streamVm := vmutils.Must(vmlib.CreateLuaVm())
+	var streamOut bytes.Buffer
+	streamIn := vmutils.Must(stream.Create(streamVm, stream.NewReader(strings.NewReader("first\r\nsecond\nthird\nDATA-rest"))))
+	streamOutUd := vmutils.Must(stream.Create(streamVm, stream.NewWriter(&streamOut)))
+	vmutils.MustOk(streamVm.Globals().Set(vmlib.GoString("input"), streamIn.ToValue()))
+	vmutils.MustOk(streamVm.Globals().Set(vmlib.GoString("output"), streamOutUd.ToValue()))
+	streamChunk := vmutils.Must(streamVm.LoadChunk(vmlib.ChunkOpts{
+		Name: "stream",
+		Code: `
+			assert(typeof(input) == "Stream")
+			output:write("1:", input:readline(), "\n")
+			local n = 0
+			for line in input:lines() do
+				n += 1
+				output:write(line:upper(), "\n")
+				if n == 2 then break end
+			end
+			local b = buffer.create(4)
+			assert(input:read(b) == 4)
+			output:write(b, input:read(1), input:read())
+			assert(input:read() == nil and input:readline() == nil)
+			output:close()
+			local ok, err = pcall(function() output:write("late") end)
+			assert(not ok and string.find(tostring(err), "stream is closed"), tostring(err))
+			return n
+		`,
+	}))
+	vmutils.Must(streamChunk.Call())
+	if got := streamOut.String(); got != "1:first\nSECOND\nTHIRD\nDATA-rest" {
+		panic(fmt.Sprintf("unexpected stream output %q", got))
+	}
+	offsetIn := vmutils.Must(stream.Create(streamVm, stream.NewReader(strings.NewReader("xyz"))))
+	offsetChunk := vmutils.Must(streamVm.LoadChunk(vmlib.ChunkOpts{
+		Name: "streamoffset",
+		Code: `
+			local src = ...
+			local b = buffer.create(5)
+			assert(src:read(b, 1, 3) == 3)
+			assert(not pcall(function() src:read(b, 4, 2) end), "reads past the end of the buffer should fail")
+			return buffer.tostring(b)
+		`,
+	}))
+	offsetRes := vmutils.Must(offsetChunk.Call(offsetIn.ToValue()))
+	if got := vmutils.Must(vmutils.FromValue[string](offsetRes[0])); got != "\x00xyz\x00" {
+		panic(fmt.Sprintf("unexpected buffer contents after reading at an offset %q", got))
+	}
+	streamVm.Close()
+	fmt.Println("Stream test passed")
 }
 
 // NewMapFs returns a new FileSystem from the provided map.
@@ -924,6 +2197,15 @@ func createDirEntry(ents []string, fim map[string]mapFI) []fs.DirEntry {
 }
 
 // mapFI is the map-based implementation of FileInfo.
+// A requirer whose HasModule always panics
+type panickyRequirer struct {
+	vmlib.Require
+}
+
+func (panickyRequirer) HasModule() bool {
+	panic("no module today")
+}
+
 type mapFI struct {
 	name string
 	size int
@@ -959,3 +2241,130 @@ func (nc nopCloser) Close() error { return nil }
 func (nc nopCloser) Stat() (os.FileInfo, error) {
 	return nil, fs.ErrInvalid
 }
+
+type bindVec struct {
+	X, Y float64
+}
+
+func (v *bindVec) Length() float64 {
+	return math.Sqrt(v.X*v.X + v.Y*v.Y)
+}
+
+type bindPlayer struct {
+	Name   string
+	Health int    `luau:"hp"`
+	Secret string `luau:"-"`
+	Pos    bindVec
+	Tags   []string
+}
+
+func (p *bindPlayer) Damage(n int) (int, error) {
+	if n < 0 {
+		return 0, errors.New("negative damage")
+	}
+	p.Health -= n
+	return p.Health, nil
+}
+
+func (p *bindPlayer) Scale(v *bindVec, factor float64) bindVec {
+	return bindVec{X: v.X * factor, Y: v.Y * factor}
+}
+
+type convNode struct {
+	Name string
+	Next *convNode
+}
+
+type convBase struct {
+	ID int
+}
+
+type convPoint struct {
+	X, Y float64
+}
+
+type convProbe struct {
+	convBase
+	Limit *int
+}
+
+func (p *convProbe) Not(b bool) bool                  { return !b }
+func (p *convProbe) Inc(n int) int                    { return n + 1 }
+func (p *convProbe) Int8(n int8) int8                 { return n }
+func (p *convProbe) Uint(n uint) uint                 { return n }
+func (p *convProbe) Double(f float32) float32         { return f * 2 }
+func (p *convProbe) Shout(s string) string            { return s + "!" }
+func (p *convProbe) Size(b []byte) int                { return len(b) }
+func (p *convProbe) Join(a [2]string) string          { return a[0] + "-" + a[1] }
+func (p *convProbe) Norm(v convPoint) float64         { return math.Sqrt(v.X*v.X + v.Y*v.Y) }
+func (p *convProbe) Kind(v any) string                { return fmt.Sprintf("%T", v) }
+func (p *convProbe) Len(v []any) int                  { return len(v) }
+func (p *convProbe) Keys(m map[any]any) int           { return len(m) }
+func (p *convProbe) IsFunc(f *vmlib.LuaFunction) bool { return f != nil }
+func (p *convProbe) Repeat(s string, n int) string    { return strings.Repeat(s, n) }
+
+func (p *convProbe) Sum(v []int) int {
+	sum := 0
+	for _, n := range v {
+		sum += n
+	}
+	return sum
+}
+
+func (p *convProbe) Total(m map[string]int) int {
+	total := 0
+	for _, n := range m {
+		total += n
+	}
+	return total
+}
+
+func (p *convProbe) Deref(n *int) int {
+	if n == nil {
+		return -1
+	}
+	return *n
+}
+
+type Identified interface {
+	GetID() int
+}
+
+type Entity struct {
+	ID int
+}
+
+func (e *Entity) GetID() int { return e.ID }
+
+type NPC struct {
+	Entity
+	Name string
+}
+
+type trackingCloser struct {
+	closed bool
+}
+
+func (c *trackingCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+type Vec2 struct {
+	X, Y float64
+}
+
+func (v Vec2) String() string {
+	return fmt.Sprintf("(%g, %g)", v.X, v.Y)
+}
+
+func appendNumber(s string, n int) (string, error) {
+	return s + strconv.Itoa(n), nil
+}
+
+func greet(name string, greeting *string) string {
+	if greeting == nil {
+		return "hello " + name
+	}
+	return *greeting + " " + name
+}
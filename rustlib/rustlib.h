@@ -28,6 +28,25 @@ struct GoNoneResult luago_setglobals(struct Lua* ptr, struct LuaTable* globals);
 struct LuaThread* luago_current_thread(struct Lua* ptr);
 void luago_set_type_metatable(struct Lua* ptr, uint8_t typ, struct LuaTable* mt);
 void freeluavm(struct Lua* ptr);
+void luago_close_luavm(struct Lua* ptr); // Frees a VM created by newluavm
+struct Lua* luago_clone_luavm(struct Lua* ptr); // Must be freed using freeluavm
+
+// GC API
+struct GcTuning {
+    // A value of 0 or less leaves the parameter unchanged
+    int32_t goal;
+    int32_t step_multiplier;
+    int32_t step_size;
+};
+struct GoNoneResult luago_gc_collect(struct Lua* ptr);
+struct GoBoolResult luago_gc_step(struct Lua* ptr, int32_t kbytes);
+void luago_gc_stop(struct Lua* ptr);
+void luago_gc_restart(struct Lua* ptr);
+bool luago_gc_is_running(struct Lua* ptr);
+int32_t luago_gc_state(struct Lua* ptr);
+struct GoNoneResult luago_gc_set_tuning(struct Lua* ptr, struct GcTuning tuning, struct GcTuning* prev);
+struct GoNoneResult luago_memory_category_usage(struct Lua* ptr, size_t* out, size_t n);
+size_t luago_memory_category_bytes(struct Lua* ptr, uint8_t category);
 
 typedef void (*Callback)(void* val, uintptr_t handle);
 typedef void (*DropCallback)(uintptr_t handle);
@@ -50,6 +69,8 @@ void luago_string_free(char* result_error_ptr);
 
 // Returns a GoResult[LuaString]
 struct GoStringResult luago_create_string(struct Lua* ptr, const char* str, size_t len);
+// Returns a GoResult[LuaString] holding the bytecode
+struct GoStringResult luago_compile(struct Lua* ptr, const char* str, size_t len, struct CompilerOpts opts);
 struct LuaString;
 
 struct LuaStringBytes {
@@ -168,6 +189,35 @@ struct LuaTable* luago_function_environment(struct LuaFunction* ptr);
 struct GoBoolResult luago_function_set_environment(struct LuaFunction* ptr, struct LuaTable* table);
 bool luago_function_equals(struct LuaFunction* a, struct LuaFunction* b);
 void luago_free_function(struct LuaFunction* f);
+struct GoFunctionInfo {
+    // Go takes ownership of the strings below (may be NULL)
+    char* name;
+    char* what;
+    char* source;
+    char* short_src;
+    int32_t line_defined; // -1 if unknown
+    uint8_t num_params;
+    bool is_vararg;
+    uint8_t num_upvalues;
+    char* error;
+};
+struct GoFunctionInfo luago_function_info(struct Lua* lua, struct LuaFunction* f);
+struct GoStackFrame {
+    // Go takes ownership of the strings below (may be NULL)
+    char* name;
+    char* what;
+    char* source;
+    int32_t line;
+};
+struct GoTracebackCallResult {
+    struct GoMultiValue* value;
+    char* error;
+    // Stack frames captured at the point of the error (if any)
+    struct GoStackFrame* frames;
+    size_t nframes;
+};
+struct GoTracebackCallResult luago_function_call_traceback(struct Lua* lua, struct LuaFunction* ptr, struct GoMultiValue* args);
+void luago_free_stack_frames(struct GoStackFrame* frames, size_t nframes);
 
 // Userdata API
 struct LuaUserData;
@@ -191,6 +241,7 @@ struct GoNoneResult luago_thread_sandbox(struct LuaThread* ptr);
 struct GoMultiValueResult luago_thread_resume(struct LuaThread* ptr, struct GoMultiValue* args);
 struct GoMultiValueResult luago_thread_resume_error(struct LuaThread* ptr, struct GoLuaValue error);
 uintptr_t luago_thread_to_pointer(struct LuaThread* ptr);
+void luago_thread_set_memory_category(struct LuaThread* ptr, uint8_t category);
 struct GoNoneResult luago_yield_with(struct Lua* ptr, struct GoMultiValue* args);
 bool luago_thread_equals(struct LuaThread* a, struct LuaThread* b);
 void luago_free_thread(struct LuaThread* ptr);
@@ -198,9 +249,12 @@ void luago_free_thread(struct LuaThread* ptr);
 // Buffer API
 struct LuaBuffer;
 struct GoBufferResult luago_create_buffer(struct Lua* ptr, const char* s, size_t len);
+struct GoBufferResult luago_create_buffer_with_capacity(struct Lua* ptr, size_t len);
 uintptr_t luago_buffer_to_pointer(struct LuaBuffer* ptr);
 bool luago_buffer_equals(struct LuaBuffer* a, struct LuaBuffer* b);
 struct LuaStringBytes luago_buffer_to_bytes(struct LuaBuffer* ptr);
+// Returns the data of the buffer without copying it
+struct LuaStringBytes luago_buffer_data(struct LuaBuffer* ptr);
 struct LuaStringBytes luago_buffer_read_bytes(struct LuaBuffer* ptr, size_t offset, size_t len);
 void luago_buffer_write_bytes(struct LuaBuffer* ptr, size_t offset, const char* bytes, size_t len);
 void luago_buffer_free_bytes(struct LuaStringBytes bytes);
@@ -321,6 +375,7 @@ struct GoValueResult luago_named_registry_value(struct Lua* ptr, const char* key
 struct GoNavigationResult {
     bool not_found;
     bool ambiguous;
+    bool denied; // Set along with other (the reason) if the require was denied by a policy
     char* other; // Rust will deallocate this automatically. Should be allocated with moveString
 };
 
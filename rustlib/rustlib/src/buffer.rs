@@ -24,6 +24,20 @@ pub extern "C" fn luago_create_buffer(ptr: *mut mluau::Lua, s: *const c_char, le
     })
 }
 
+// Creates a zero-filled buffer of the given size without copying any data into it
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_create_buffer_with_capacity(ptr: *mut mluau::Lua, len: usize) -> GoBufferResult  {
+    wrap_failable(|| {
+        // Safety: Assume ptr is a valid, non-null pointer to a Lua
+        let lua = unsafe { &*ptr };
+
+        match lua.create_buffer_with_capacity(len) {
+            Ok(buf) => GoBufferResult::ok(Box::into_raw(Box::new(buf))),
+            Err(err) => crate::result::GoBufferResult::err(format!("{err}"))
+        }
+    })
+}
+
 #[unsafe(no_mangle)]
 pub extern "C" fn luago_buffer_to_pointer(buf: *mut mluau::Buffer) -> usize {
     wrap_failable(|| {
@@ -92,6 +106,27 @@ pub extern "C" fn luago_buffer_to_bytes(buf: *mut mluau::Buffer) -> LuaStringByt
     })
 }
 
+// Returns a pointer to the data of the buffer without copying it
+//
+// Luau does not move buffers, so the data stays valid for as long as the buffer is referenced.
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_buffer_data(buf: *mut mluau::Buffer) -> LuaStringBytes {
+    wrap_failable(|| {
+        if buf.is_null() {
+            return LuaStringBytes {
+                data: std::ptr::null(),
+                size: 0,
+            };
+        }
+
+        let buf = unsafe { &*buf };
+        LuaStringBytes {
+            data: buf.to_pointer() as *const u8,
+            size: buf.len(),
+        }
+    })
+}
+
 #[unsafe(no_mangle)]
 pub extern "C" fn luago_buffer_read_bytes(buf: *mut mluau::Buffer, offset: usize, len: usize) -> LuaStringBytes {
     wrap_failable(|| {
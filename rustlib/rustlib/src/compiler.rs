@@ -1,3 +1,7 @@
+use std::ffi::c_char;
+
+use crate::result::{wrap_failable, GoStringResult};
+
 #[repr(C)]
 #[derive(Clone)]
 pub struct CompilerOpts {
@@ -20,4 +24,30 @@ impl CompilerOpts {
         compiler = compiler.set_coverage_level(self.coverage_level);
         compiler
     }
-}
\ No newline at end of file
+}
+
+// Compiles Luau source code to bytecode, returned as a Lua string
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_compile(ptr: *mut mluau::Lua, s: *const c_char, len: usize, opts: CompilerOpts) -> GoStringResult {
+    wrap_failable(|| {
+        // Safety: Assume ptr is a valid, non-null pointer to a Lua
+        // and that s points to a valid string of length len.
+        let lua = unsafe { &*ptr };
+
+        let source: &[u8] = if s.is_null() {
+            &[]
+        } else {
+            unsafe { std::slice::from_raw_parts(s as *const u8, len) }
+        };
+
+        let bytecode = match opts.to_compiler().compile(source) {
+            Ok(bytecode) => bytecode,
+            Err(err) => return GoStringResult::err(format!("{err}")),
+        };
+
+        match lua.create_string(bytecode) {
+            Ok(str) => GoStringResult::ok(Box::into_raw(Box::new(str))),
+            Err(err) => GoStringResult::err(format!("{err}"))
+        }
+    })
+}
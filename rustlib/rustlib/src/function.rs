@@ -1,6 +1,8 @@
-use std::ffi::{c_char, c_void, CString};
+use std::ffi::{c_char, c_void, CStr, CString};
 
-use crate::{multivalue::GoMultiValue, result::{wrap_failable, GoBoolResult, GoFunctionResult, GoMultiValueResult}, IGoCallback, IGoCallbackWrapper};
+use mluau::ffi;
+
+use crate::{multivalue::GoMultiValue, result::{to_c_string, wrap_failable, Errorable, GoBoolResult, GoFunctionResult, GoMultiValueResult}, IGoCallback, IGoCallbackWrapper};
 
 #[repr(C)]
 // NOTE: Aside from the Lua, Rust will deallocate everything
@@ -196,4 +198,287 @@ pub extern "C" fn luago_free_function(f: *mut mluau::Function) {
         // Re-box the Lua function pointer to manage its memory automatically.
         unsafe { drop(Box::from_raw(f)) };
     })
-}
\ No newline at end of file
+}
+#[repr(C)]
+pub struct GoFunctionInfo {
+    // All strings are owned by Go after being returned
+    pub name: *mut c_char,
+    pub what: *mut c_char,
+    pub source: *mut c_char,
+    pub short_src: *mut c_char,
+    pub line_defined: i32,
+    pub num_params: u8,
+    pub is_vararg: bool,
+    pub num_upvalues: u8,
+    pub error: *mut c_char,
+}
+
+impl GoFunctionInfo {
+    fn empty() -> Self {
+        Self {
+            name: std::ptr::null_mut(),
+            what: std::ptr::null_mut(),
+            source: std::ptr::null_mut(),
+            short_src: std::ptr::null_mut(),
+            line_defined: -1,
+            num_params: 0,
+            is_vararg: false,
+            num_upvalues: 0,
+            error: std::ptr::null_mut(),
+        }
+    }
+
+    // Frees any strings that have not been handed over to Go yet
+    fn free_strings(&mut self) {
+        for s in [&mut self.name, &mut self.what, &mut self.source, &mut self.short_src] {
+            if !s.is_null() {
+                unsafe { drop(CString::from_raw(*s)) };
+                *s = std::ptr::null_mut();
+            }
+        }
+    }
+}
+
+impl Errorable for GoFunctionInfo {
+    fn error_variant(s: String) -> Self {
+        let mut info = Self::empty();
+        info.error = to_c_string(s);
+        info
+    }
+}
+
+fn cstr_to_c_string(ptr: *const c_char) -> *mut c_char {
+    if ptr.is_null() {
+        return std::ptr::null_mut();
+    }
+    let s = unsafe { CStr::from_ptr(ptr) };
+    to_c_string(s.to_string_lossy().into_owned())
+}
+
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_function_info(lua: *mut mluau::Lua, f: *mut mluau::Function) -> GoFunctionInfo {
+    wrap_failable(|| {
+        if lua.is_null() {
+            return GoFunctionInfo::error_variant("Lua pointer is null".to_string());
+        }
+        if f.is_null() {
+            return GoFunctionInfo::error_variant("LuaFunction pointer is null".to_string());
+        }
+
+        let lua = unsafe { &*lua };
+        let lua_f = unsafe { &*f };
+
+        let mut info = GoFunctionInfo::empty();
+        // Safety: lua_getinfo only reads the function at the top of the stack
+        // and does not raise errors
+        let res = unsafe {
+            lua.exec_raw::<()>(lua_f.clone(), |state| {
+                let mut ar: ffi::lua_Debug = std::mem::zeroed();
+                if ffi::lua_getinfo(state, -1, c"snau".as_ptr(), &mut ar) == 0 {
+                    return;
+                }
+                info.name = cstr_to_c_string(ar.name);
+                info.what = cstr_to_c_string(ar.what);
+                info.source = cstr_to_c_string(ar.source);
+                info.short_src = cstr_to_c_string(ar.short_src);
+                info.line_defined = ar.linedefined;
+                info.num_params = ar.nparams;
+                info.is_vararg = ar.isvararg != 0;
+                info.num_upvalues = ar.nupvals;
+                ffi::lua_pop(state, 1);
+            })
+        };
+
+        match res {
+            Ok(()) => info,
+            Err(e) => {
+                info.free_strings();
+                GoFunctionInfo::error_variant(format!("{e}"))
+            }
+        }
+    })
+}
+
+#[repr(C)]
+pub struct GoStackFrame {
+    // Go takes ownership of the strings below (may be null)
+    pub name: *mut c_char,
+    pub what: *mut c_char,
+    pub source: *mut c_char,
+    pub line: i32,
+}
+
+#[repr(C)]
+pub struct GoTracebackCallResult {
+    pub value: *mut GoMultiValue,
+    pub error: *mut c_char,
+    // Stack frames captured at the point of the error (if any)
+    pub frames: *mut GoStackFrame,
+    pub nframes: usize,
+}
+
+impl GoTracebackCallResult {
+    fn ok(mv: mluau::MultiValue) -> Self {
+        Self {
+            value: GoMultiValue::inst(mv),
+            error: std::ptr::null_mut(),
+            frames: std::ptr::null_mut(),
+            nframes: 0,
+        }
+    }
+
+    fn err_with_frames(error: String, frames: Vec<GoStackFrame>) -> Self {
+        let nframes = frames.len();
+        let frames = if nframes == 0 {
+            std::ptr::null_mut()
+        } else {
+            Box::into_raw(frames.into_boxed_slice()) as *mut GoStackFrame
+        };
+        Self {
+            value: std::ptr::null_mut(),
+            error: to_c_string(error),
+            frames,
+            nframes,
+        }
+    }
+}
+
+impl Errorable for GoTracebackCallResult {
+    fn error_variant(s: String) -> Self {
+        Self::err_with_frames(s, Vec::new())
+    }
+}
+
+// Message handler used by luago_function_call_traceback
+//
+// Replaces the error with a table of the form { message = string, frames = { { name, what, source, line }... } }
+// captured before the stack is unwound. This only uses lua_getinfo and hence works without the debug library
+unsafe extern "C-unwind" fn traceback_handler(state: *mut ffi::lua_State) -> std::ffi::c_int {
+    unsafe {
+        ffi::luaL_checkstack(state, 6, c"traceback".as_ptr());
+        ffi::luaL_tolstring(state, 1, std::ptr::null_mut()); // message at index 2
+        ffi::lua_createtable(state, 0, 0); // frames at index 3
+
+        let mut level = 1;
+        let mut n = 0;
+        let mut ar: ffi::lua_Debug = std::mem::zeroed();
+        while ffi::lua_getinfo(state, level, c"sln".as_ptr(), &mut ar) != 0 {
+            ffi::lua_createtable(state, 0, 4);
+            if !ar.name.is_null() {
+                ffi::lua_pushstring(state, ar.name);
+                ffi::lua_setfield(state, -2, c"name".as_ptr());
+            }
+            if !ar.what.is_null() {
+                ffi::lua_pushstring(state, ar.what);
+                ffi::lua_setfield(state, -2, c"what".as_ptr());
+            }
+            if !ar.short_src.is_null() {
+                ffi::lua_pushstring(state, ar.short_src);
+                ffi::lua_setfield(state, -2, c"source".as_ptr());
+            }
+            ffi::lua_pushinteger(state, ar.currentline as _);
+            ffi::lua_setfield(state, -2, c"line".as_ptr());
+            n += 1;
+            ffi::lua_rawseti(state, 3, n);
+            level += 1;
+        }
+
+        ffi::lua_createtable(state, 0, 2);
+        ffi::lua_pushvalue(state, 2);
+        ffi::lua_setfield(state, -2, c"message".as_ptr());
+        ffi::lua_pushvalue(state, 3);
+        ffi::lua_setfield(state, -2, c"frames".as_ptr());
+        1
+    }
+}
+
+fn frames_from_table(frames: mluau::Table) -> Vec<GoStackFrame> {
+    let mut out = Vec::new();
+    for frame in frames.sequence_values::<mluau::Table>() {
+        let Ok(frame) = frame else { continue };
+        let opt_str = |key: &str| match frame.raw_get::<Option<String>>(key) {
+            Ok(Some(s)) => to_c_string(s),
+            _ => std::ptr::null_mut(),
+        };
+        out.push(GoStackFrame {
+            name: opt_str("name"),
+            what: opt_str("what"),
+            source: opt_str("source"),
+            line: frame.raw_get::<i32>("line").unwrap_or(-1),
+        });
+    }
+    out
+}
+
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_function_call_traceback(lua: *mut mluau::Lua, ptr: *mut mluau::Function, args: *mut GoMultiValue) -> GoTracebackCallResult {
+    wrap_failable(|| {
+        if lua.is_null() {
+            return GoTracebackCallResult::error_variant("Lua pointer is null".to_string());
+        }
+        if ptr.is_null() {
+            return GoTracebackCallResult::error_variant("Function pointer is null".to_string());
+        }
+
+        let lua = unsafe { &*lua };
+        let func = unsafe { &*ptr };
+
+        // Safety: Go side must ensure values cannot be used after it is set
+        // here as a return value
+        let values = unsafe { Box::from_raw(args) };
+        let mut values_mv = values.values.into_inner().unwrap();
+
+        let handler = match unsafe { lua.create_c_function(traceback_handler) } {
+            Ok(h) => h,
+            Err(e) => return GoTracebackCallResult::error_variant(format!("{e}")),
+        };
+
+        let nargs = values_mv.len();
+        values_mv.push_front(mluau::Value::Function(func.clone()));
+        values_mv.push_front(mluau::Value::Function(handler));
+
+        let mut status = 0;
+        // Safety: the stack is [handler, func, args...] when the closure is called
+        let res = unsafe {
+            lua.exec_raw::<mluau::MultiValue>(values_mv, |state| {
+                status = ffi::lua_pcall(state, nargs as _, ffi::LUA_MULTRET, 1);
+                ffi::lua_remove(state, 1); // Remove the handler
+            })
+        };
+
+        let mv = match res {
+            Ok(mv) => mv,
+            Err(e) => return GoTracebackCallResult::error_variant(format!("{e}")),
+        };
+
+        if status == ffi::LUA_OK {
+            return GoTracebackCallResult::ok(mv);
+        }
+
+        match mv.into_iter().next() {
+            Some(mluau::Value::Table(t)) => {
+                let message = t.raw_get::<String>("message").unwrap_or_else(|_| "unknown error".to_string());
+                let frames = match t.raw_get::<Option<mluau::Table>>("frames") {
+                    Ok(Some(frames)) => frames_from_table(frames),
+                    _ => Vec::new(),
+                };
+                GoTracebackCallResult::err_with_frames(message, frames)
+            }
+            // The message handler itself failed (e.g. LUA_ERRERR or LUA_ERRMEM)
+            Some(v) => GoTracebackCallResult::error_variant(v.to_string().unwrap_or_else(|_| "error in error handling".to_string())),
+            None => GoTracebackCallResult::error_variant("unknown error".to_string()),
+        }
+    })
+}
+
+// Frees the frames array returned by luago_function_call_traceback
+//
+// Go must have taken ownership of the strings inside each frame beforehand
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_free_stack_frames(frames: *mut GoStackFrame, nframes: usize) {
+    if frames.is_null() {
+        return;
+    }
+
+    unsafe { drop(Box::from_raw(std::ptr::slice_from_raw_parts_mut(frames, nframes))) };
+}
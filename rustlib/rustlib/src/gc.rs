@@ -0,0 +1,258 @@
+use std::collections::HashMap;
+use std::ffi::c_int;
+use std::sync::{LazyLock, RwLock};
+
+use mluau::ffi;
+
+use crate::result::{wrap_failable, GoBoolResult, GoNoneResult};
+
+// Number of memory categories supported by Luau
+pub const LUA_MEMORY_CATEGORIES: usize = 256;
+
+type InterruptFn = unsafe extern "C-unwind" fn(*mut ffi::lua_State, c_int);
+
+// The GC state last reported by Luau for a VM and the interrupt installed by mluau (if any)
+struct GcObserver {
+    state: c_int,
+    interrupt: Option<InterruptFn>,
+}
+
+// GC observers keyed by the main thread of their VM
+static GC_OBSERVERS: LazyLock<RwLock<HashMap<usize, GcObserver>>> = LazyLock::new(Default::default);
+
+// Luau calls the interrupt with the state of the GC after every GC step (and with -1 at
+// other safepoints), which is the only way it exposes the state. The call is forwarded
+// to the interrupt set by mluau.
+unsafe extern "C-unwind" fn gc_observer_interrupt(state: *mut ffi::lua_State, gc: c_int) {
+    let main = unsafe { ffi::lua_mainthread(state) } as usize;
+    let interrupt = if gc >= 0 {
+        let mut observers = GC_OBSERVERS.write().unwrap();
+        observers.get_mut(&main).and_then(|observer| {
+            observer.state = gc;
+            observer.interrupt
+        })
+    } else {
+        GC_OBSERVERS.read().unwrap().get(&main).and_then(|observer| observer.interrupt)
+    };
+
+    if let Some(interrupt) = interrupt {
+        unsafe { interrupt(state, gc) }
+    }
+}
+
+// Installs the GC observer of a VM, keeping the interrupt currently set by mluau
+//
+// This must be called again whenever mluau changes the interrupt.
+pub fn install_gc_observer(lua: &mluau::Lua) {
+    // Safety: only the callbacks of the main thread are read and changed
+    let _ = unsafe {
+        lua.exec_raw::<()>((), |state| {
+            let main = ffi::lua_mainthread(state);
+            let callbacks = ffi::lua_callbacks(main);
+            let current = (*callbacks).interrupt;
+
+            let mut observers = GC_OBSERVERS.write().unwrap();
+            let observer = observers.entry(main as usize).or_insert(GcObserver { state: 0, interrupt: None });
+            if current.map(|f| f as usize) != Some(gc_observer_interrupt as InterruptFn as usize) {
+                observer.interrupt = current;
+            }
+            (*callbacks).interrupt = Some(gc_observer_interrupt);
+        })
+    };
+}
+
+// Removes the GC observer of a VM which is about to be freed
+pub fn remove_gc_observer(lua: &mluau::Lua) {
+    // Safety: lua_mainthread only reads the state
+    let _ = unsafe {
+        lua.exec_raw::<()>((), |state| {
+            GC_OBSERVERS.write().unwrap().remove(&(ffi::lua_mainthread(state) as usize));
+        })
+    };
+}
+
+// Returns the state of the GC during its last step (0 if the GC has not run yet)
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_gc_state(ptr: *mut mluau::Lua) -> i32 {
+    wrap_failable(|| {
+        if ptr.is_null() {
+            return 0;
+        }
+        let lua = unsafe { &*ptr };
+
+        let mut gc = 0;
+        // Safety: lua_mainthread only reads the state
+        let _ = unsafe {
+            lua.exec_raw::<()>((), |state| {
+                let main = ffi::lua_mainthread(state) as usize;
+                if let Some(observer) = GC_OBSERVERS.read().unwrap().get(&main) {
+                    gc = observer.state;
+                }
+            })
+        };
+        gc
+    })
+}
+
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_gc_collect(ptr: *mut mluau::Lua) -> GoNoneResult {
+    wrap_failable(|| {
+        if ptr.is_null() {
+            return GoNoneResult::err("Lua pointer is null".to_string());
+        }
+        let lua = unsafe { &*ptr };
+        match lua.gc_collect() {
+            Ok(_) => GoNoneResult::ok(),
+            Err(err) => GoNoneResult::err(format!("{err}")),
+        }
+    })
+}
+
+// Performs an incremental GC step of (roughly) kbytes kilobytes of work
+//
+// Returns true if the step finished a GC cycle
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_gc_step(ptr: *mut mluau::Lua, kbytes: i32) -> GoBoolResult {
+    wrap_failable(|| {
+        if ptr.is_null() {
+            return GoBoolResult::err("Lua pointer is null".to_string());
+        }
+        let lua = unsafe { &*ptr };
+        match lua.gc_step_kbytes(kbytes) {
+            Ok(finished) => GoBoolResult::ok(finished),
+            Err(err) => GoBoolResult::err(format!("{err}")),
+        }
+    })
+}
+
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_gc_stop(ptr: *mut mluau::Lua) {
+    wrap_failable(|| {
+        if ptr.is_null() {
+            return;
+        }
+        let lua = unsafe { &*ptr };
+        lua.gc_stop();
+    })
+}
+
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_gc_restart(ptr: *mut mluau::Lua) {
+    wrap_failable(|| {
+        if ptr.is_null() {
+            return;
+        }
+        let lua = unsafe { &*ptr };
+        lua.gc_restart();
+    })
+}
+
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_gc_is_running(ptr: *mut mluau::Lua) -> bool {
+    wrap_failable(|| {
+        if ptr.is_null() {
+            return false;
+        }
+        let lua = unsafe { &*ptr };
+        lua.gc_is_running()
+    })
+}
+
+#[repr(C)]
+pub struct GcTuning {
+    // A value of 0 or less leaves the parameter unchanged
+    pub goal: i32,
+    pub step_multiplier: i32,
+    pub step_size: i32,
+}
+
+// Sets the GC tuning parameters, returning the previous ones
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_gc_set_tuning(ptr: *mut mluau::Lua, tuning: GcTuning, prev: *mut GcTuning) -> GoNoneResult {
+    wrap_failable(|| {
+        if ptr.is_null() {
+            return GoNoneResult::err("Lua pointer is null".to_string());
+        }
+        let lua = unsafe { &*ptr };
+
+        let mut old = GcTuning { goal: 0, step_multiplier: 0, step_size: 0 };
+        // Safety: lua_gc with the below options does not raise errors
+        let res = unsafe {
+            lua.exec_raw::<()>((), |state| {
+                // Luau has no 'get' for these, so set and restore if unchanged
+                let set = |what, value: i32| {
+                    if value > 0 {
+                        ffi::lua_gc(state, what, value)
+                    } else {
+                        let current = ffi::lua_gc(state, what, 0);
+                        ffi::lua_gc(state, what, current);
+                        current
+                    }
+                };
+                old.goal = set(ffi::LUA_GCSETGOAL, tuning.goal);
+                old.step_multiplier = set(ffi::LUA_GCSETSTEPMUL, tuning.step_multiplier);
+                old.step_size = set(ffi::LUA_GCSETSTEPSIZE, tuning.step_size);
+            })
+        };
+
+        match res {
+            Ok(()) => {
+                if !prev.is_null() {
+                    unsafe { *prev = old };
+                }
+                GoNoneResult::ok()
+            }
+            Err(err) => GoNoneResult::err(format!("{err}")),
+        }
+    })
+}
+
+// Fills out (which must have room for n entries) with the number of bytes
+// allocated in each memory category
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_memory_category_usage(ptr: *mut mluau::Lua, out: *mut usize, n: usize) -> GoNoneResult {
+    wrap_failable(|| {
+        if ptr.is_null() {
+            return GoNoneResult::err("Lua pointer is null".to_string());
+        }
+        if out.is_null() {
+            return GoNoneResult::err("output pointer is null".to_string());
+        }
+        let lua = unsafe { &*ptr };
+        let out = unsafe { std::slice::from_raw_parts_mut(out, n.min(LUA_MEMORY_CATEGORIES)) };
+
+        // Safety: lua_totalbytes only reads the global state
+        let res = unsafe {
+            lua.exec_raw::<()>((), |state| {
+                for (category, usage) in out.iter_mut().enumerate() {
+                    *usage = ffi::lua_totalbytes(state, category as _);
+                }
+            })
+        };
+
+        match res {
+            Ok(()) => GoNoneResult::ok(),
+            Err(err) => GoNoneResult::err(format!("{err}")),
+        }
+    })
+}
+
+// Returns the number of bytes allocated in a single memory category
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_memory_category_bytes(ptr: *mut mluau::Lua, category: u8) -> usize {
+    wrap_failable(|| {
+        if ptr.is_null() {
+            return 0;
+        }
+        let lua = unsafe { &*ptr };
+
+        let mut bytes = 0;
+        // Safety: lua_totalbytes only reads the global state
+        let _ = unsafe {
+            lua.exec_raw::<()>((), |state| {
+                bytes = ffi::lua_totalbytes(state, category as _);
+            })
+        };
+        bytes
+    })
+}
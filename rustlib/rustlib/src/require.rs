@@ -7,6 +7,7 @@ use std::ffi::{c_char, c_void, CString};
 pub struct GoNavigationResult {
     not_found: bool,
     ambiguous: bool,
+    denied: bool, // Set along with other (the reason) if the require was denied by a policy
     other: *mut c_char // Rust will deallocate this automatically. Should be allocated with moveStringToRust
 }
 
@@ -15,6 +16,7 @@ impl GoNavigationResult {
         Self {
             not_found: false,
             ambiguous: false,
+            denied: false,
             other: std::ptr::null_mut()
         }
     }
@@ -27,6 +29,10 @@ impl GoNavigationResult {
             Err(NavigateError::Ambiguous)
         } else if !self.other.is_null() {
             let error = unsafe { CString::from_raw(self.other) };
+            if self.denied {
+                // Denied requires are reported with the reason as-is rather than as an external error
+                return Err(NavigateError::Other(mluau::Error::RuntimeError(error.to_string_lossy().into_owned())));
+            }
             Err(NavigateError::Other(mluau::Error::external(error.to_string_lossy())))
         } else {
             Ok(())
@@ -129,6 +129,23 @@ pub extern "C" fn luago_thread_to_pointer(t: *mut mluau::Thread) -> usize {
     })
 }
 
+// Sets the memory category new allocations made by the thread are attributed to
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_thread_set_memory_category(t: *mut mluau::Thread, category: u8) {
+    wrap_failable(|| {
+        // Safety: Assume thread is a valid, non-null pointer to a Lua thread
+        if t.is_null() {
+            return;
+        }
+
+        let lua_t = unsafe { &*t };
+
+        // In Luau, the pointer of a thread is its lua_State
+        let state = lua_t.to_pointer() as *mut mluau::ffi::lua_State;
+        unsafe { mluau::ffi::lua_setmemcat(state, category as _) };
+    })
+}
+
 #[unsafe(no_mangle)]
 pub extern "C" fn luago_thread_equals(t: *mut mluau::Thread, t2: *mut mluau::Thread) -> bool {
     wrap_failable(|| {
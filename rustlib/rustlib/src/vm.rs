@@ -2,7 +2,7 @@ use std::ffi::{c_char, c_void, CString};
 
 use mluau::Lua;
 
-use crate::{compiler::CompilerOpts, multivalue::GoMultiValue, result::{wrap_failable, GoNoneResult, GoValueResult}, value::GoLuaValue, IGoCallback, IGoCallbackWrapper};
+use crate::{compiler::CompilerOpts, gc::{install_gc_observer, remove_gc_observer}, multivalue::GoMultiValue, result::{wrap_failable, GoNoneResult, GoValueResult}, value::GoLuaValue, IGoCallback, IGoCallbackWrapper};
 
 // Represents the different standard libraries that can be loaded into the Luau VM.
 bitflags::bitflags! {
@@ -73,10 +73,7 @@ pub extern "C" fn newluavm(stdlib: u32) -> *mut mluau::Lua {
             .catch_rust_panics(false)
             .disable_error_userdata(true)
         ).unwrap(); // Will never error, as we are using safe libraries only.
-
-        lua.set_on_close(|| {
-            println!("Lua VM is being closed");
-        });
+        install_gc_observer(&lua);
 
         let wrapper = Box::new(lua);
         Box::into_raw(wrapper)
@@ -201,6 +198,7 @@ pub extern "C" fn luago_set_interrupt(ptr: *mut mluau::Lua, cb: IGoCallback)  {
                 _ => Err(mluau::Error::external("Invalid VM state".to_string())),
             }
         });
+        install_gc_observer(lua);
     })
 }
 
@@ -214,6 +212,7 @@ pub extern "C" fn luago_remove_interrupt(ptr: *mut mluau::Lua)  {
 
         let lua = unsafe { &*ptr };
         lua.remove_interrupt();
+        install_gc_observer(lua);
     })
 }
 
@@ -355,6 +354,33 @@ pub extern "C" fn luago_named_registry_value(ptr: *mut mluau::Lua, key: *const c
     })
 }
 
+// Returns a new handle to a VM, which keeps it alive until freed using freeluavm
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_clone_luavm(ptr: *mut mluau::Lua) -> *mut mluau::Lua {
+    wrap_failable(|| {
+        if ptr.is_null() {
+            return std::ptr::null_mut();
+        }
+        let lua = unsafe { &*ptr };
+        Box::into_raw(Box::new(lua.clone()))
+    })
+}
+
+// Frees a VM created by newluavm (handles passed to callbacks are freed using freeluavm)
+#[unsafe(no_mangle)]
+pub extern "C" fn luago_close_luavm(ptr: *mut mluau::Lua) {
+    wrap_failable(|| {
+        if ptr.is_null() {
+            return;
+        }
+        // Safety: Assume ptr is a valid pointer to a mluau::Lua created by newluavm
+        // and that ownership is being transferred back to Rust to be dropped.
+        let lua = unsafe { Box::from_raw(ptr) };
+        remove_gc_observer(&lua);
+        drop(lua);
+    })
+}
+
 #[unsafe(no_mangle)]
 pub extern "C" fn freeluavm(ptr: *mut mluau::Lua) {
     wrap_failable(|| {
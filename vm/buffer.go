@@ -6,10 +6,12 @@ package vm
 import "C"
 import (
 	"fmt"
+	"math"
 	"unsafe"
 )
 
 var bufferTab = objectTab{
+	name: "LuaBuffer",
 	dtor: func(ptr *C.void) {
 		C.luago_free_buffer((*C.struct_LuaBuffer)(unsafe.Pointer(ptr)))
 	},
@@ -66,6 +68,34 @@ func (l *LuaBuffer) Bytes() []byte {
 	return bytes
 }
 
+// WithBytes calls fn with the memory of the LuaBuffer without copying it
+//
+// Writes to the slice change the buffer. The slice is only valid while fn runs and must not
+// be retained. The buffer and its Lua VM are read-locked while fn runs, so neither can be
+// closed until fn returns. The Lua VM cannot be entered from fn: creating values, calling
+// functions, resuming threads and table operations that may invoke metamethods fail with
+// ErrViewOpen, as does closing the Lua VM.
+func (l *LuaBuffer) WithBytes(fn func(b []byte) error) error {
+	if l.lua.object.IsClosed() {
+		return fmt.Errorf("cannot use a LuaBuffer of a closed Lua VM")
+	}
+	l.object.RLock()
+	defer l.object.RUnlock()
+	ptr, err := l.innerPtr()
+	if err != nil {
+		return err
+	}
+
+	data := C.luago_buffer_data(ptr)
+	var b []byte
+	if data.data != nil && data.len > 0 {
+		b = unsafe.Slice((*byte)(unsafe.Pointer(data.data)), int(data.len))
+	}
+	return l.lua.withView(func() error {
+		return fn(b)
+	})
+}
+
 // Returns the bytes from the LuaBuffer starting at the given offset
 // with the specified length.
 func (l *LuaBuffer) ReadBytes(offset, len uint64) []byte {
@@ -112,6 +142,74 @@ func (l *LuaBuffer) WriteBytes(offset uint64, data []byte) error {
 	return nil
 }
 
+// Fill sets count bytes of the LuaBuffer starting at offset to value (like buffer.fill)
+func (l *LuaBuffer) Fill(offset uint64, value byte, count uint64) error {
+	if count > math.MaxInt32 {
+		return fmt.Errorf("fill count %d is too large", count)
+	}
+	return l.access(offset, int(count), func(b []byte) {
+		for i := range b {
+			b[i] = value
+		}
+	})
+}
+
+// CopyFrom copies n bytes of src starting at srcOffset into the LuaBuffer at dstOffset
+// (like buffer.copy)
+//
+// src may be the LuaBuffer itself, in which case overlapping ranges are handled correctly.
+func (l *LuaBuffer) CopyFrom(src *LuaBuffer, srcOffset, dstOffset, n uint64) error {
+	if src == nil {
+		return fmt.Errorf("cannot copy from a nil LuaBuffer")
+	}
+	if src.lua != l.lua {
+		return fmt.Errorf("cannot copy between LuaBuffers of different Lua VMs")
+	}
+	if n > math.MaxInt32 {
+		return fmt.Errorf("copy length %d is too large", n)
+	}
+	if src.object == l.object {
+		return l.WithBytes(func(b []byte) error {
+			if err := checkRange(srcOffset, n, len(b)); err != nil {
+				return err
+			}
+			if err := checkRange(dstOffset, n, len(b)); err != nil {
+				return err
+			}
+			copy(b[dstOffset:dstOffset+n], b[srcOffset:srcOffset+n])
+			return nil
+		})
+	}
+	return src.WithBytes(func(sb []byte) error {
+		if err := checkRange(srcOffset, n, len(sb)); err != nil {
+			return err
+		}
+		return l.access(dstOffset, int(n), func(db []byte) {
+			copy(db, sb[srcOffset:srcOffset+n])
+		})
+	})
+}
+
+// ResizeCopy returns a new LuaBuffer of the given size holding the contents of the LuaBuffer
+//
+// As Luau buffers have a fixed size, this is the way to grow (or shrink) a buffer. The contents
+// are truncated when shrinking and the new bytes are zero when growing.
+func (l *LuaBuffer) ResizeCopy(size uint64) (*LuaBuffer, error) {
+	n := l.Len()
+	if size < n {
+		n = size
+	}
+	buf, err := l.lua.CreateBufferSize(size)
+	if err != nil {
+		return nil, err
+	}
+	if err := buf.CopyFrom(l, 0, 0, n); err != nil {
+		buf.Close()
+		return nil, err
+	}
+	return buf, nil
+}
+
 // Returns the LuaBuffer's length
 func (l *LuaBuffer) Len() uint64 {
 	if l.lua.object.IsClosed() {
@@ -0,0 +1,339 @@
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Calls fn with the n bytes of the buffer at offset, if they are in bounds
+func (l *LuaBuffer) access(offset uint64, n int, fn func(b []byte)) error {
+	return l.WithBytes(func(b []byte) error {
+		if err := checkRange(offset, uint64(n), len(b)); err != nil {
+			return err
+		}
+		fn(b[offset : offset+uint64(n)])
+		return nil
+	})
+}
+
+// Returns an error if the n bytes at offset are out of bounds for a buffer of the given length
+func checkRange(offset, n uint64, length int) error {
+	if offset > uint64(length) || n > uint64(length)-offset {
+		return fmt.Errorf("access of %d bytes at offset %d is out of bounds for LuaBuffer of length %d", n, offset, length)
+	}
+	return nil
+}
+
+func (l *LuaBuffer) readUint(offset uint64, n int, order binary.ByteOrder) (v uint64, err error) {
+	err = l.access(offset, n, func(b []byte) {
+		switch n {
+		case 1:
+			v = uint64(b[0])
+		case 2:
+			v = uint64(order.Uint16(b))
+		case 4:
+			v = uint64(order.Uint32(b))
+		case 8:
+			v = order.Uint64(b)
+		}
+	})
+	return v, err
+}
+
+func (l *LuaBuffer) writeUint(offset uint64, n int, order binary.ByteOrder, v uint64) error {
+	return l.access(offset, n, func(b []byte) {
+		switch n {
+		case 1:
+			b[0] = byte(v)
+		case 2:
+			order.PutUint16(b, uint16(v))
+		case 4:
+			order.PutUint32(b, uint32(v))
+		case 8:
+			order.PutUint64(b, v)
+		}
+	})
+}
+
+// Like the buffer library of Luau, the typed accessors below are little-endian unless
+// their name ends with BE (big-endian).
+
+// ReadU8 reads an unsigned 8-bit integer at offset (like buffer.readu8)
+func (l *LuaBuffer) ReadU8(offset uint64) (uint8, error) {
+	v, err := l.readUint(offset, 1, binary.LittleEndian)
+	return uint8(v), err
+}
+
+// ReadI8 reads a signed 8-bit integer at offset (like buffer.readi8)
+func (l *LuaBuffer) ReadI8(offset uint64) (int8, error) {
+	v, err := l.readUint(offset, 1, binary.LittleEndian)
+	return int8(v), err
+}
+
+// ReadU16 reads a little-endian unsigned 16-bit integer at offset (like buffer.readu16)
+func (l *LuaBuffer) ReadU16(offset uint64) (uint16, error) {
+	v, err := l.readUint(offset, 2, binary.LittleEndian)
+	return uint16(v), err
+}
+
+// ReadU16BE reads a big-endian unsigned 16-bit integer at offset
+func (l *LuaBuffer) ReadU16BE(offset uint64) (uint16, error) {
+	v, err := l.readUint(offset, 2, binary.BigEndian)
+	return uint16(v), err
+}
+
+// ReadI16 reads a little-endian signed 16-bit integer at offset (like buffer.readi16)
+func (l *LuaBuffer) ReadI16(offset uint64) (int16, error) {
+	v, err := l.readUint(offset, 2, binary.LittleEndian)
+	return int16(v), err
+}
+
+// ReadI16BE reads a big-endian signed 16-bit integer at offset
+func (l *LuaBuffer) ReadI16BE(offset uint64) (int16, error) {
+	v, err := l.readUint(offset, 2, binary.BigEndian)
+	return int16(v), err
+}
+
+// ReadU32 reads a little-endian unsigned 32-bit integer at offset (like buffer.readu32)
+func (l *LuaBuffer) ReadU32(offset uint64) (uint32, error) {
+	v, err := l.readUint(offset, 4, binary.LittleEndian)
+	return uint32(v), err
+}
+
+// ReadU32BE reads a big-endian unsigned 32-bit integer at offset
+func (l *LuaBuffer) ReadU32BE(offset uint64) (uint32, error) {
+	v, err := l.readUint(offset, 4, binary.BigEndian)
+	return uint32(v), err
+}
+
+// ReadI32 reads a little-endian signed 32-bit integer at offset (like buffer.readi32)
+func (l *LuaBuffer) ReadI32(offset uint64) (int32, error) {
+	v, err := l.readUint(offset, 4, binary.LittleEndian)
+	return int32(v), err
+}
+
+// ReadI32BE reads a big-endian signed 32-bit integer at offset
+func (l *LuaBuffer) ReadI32BE(offset uint64) (int32, error) {
+	v, err := l.readUint(offset, 4, binary.BigEndian)
+	return int32(v), err
+}
+
+// ReadF32 reads a little-endian 32-bit float at offset (like buffer.readf32)
+func (l *LuaBuffer) ReadF32(offset uint64) (float32, error) {
+	v, err := l.readUint(offset, 4, binary.LittleEndian)
+	return math.Float32frombits(uint32(v)), err
+}
+
+// ReadF32BE reads a big-endian 32-bit float at offset
+func (l *LuaBuffer) ReadF32BE(offset uint64) (float32, error) {
+	v, err := l.readUint(offset, 4, binary.BigEndian)
+	return math.Float32frombits(uint32(v)), err
+}
+
+// ReadF64 reads a little-endian 64-bit float at offset (like buffer.readf64)
+func (l *LuaBuffer) ReadF64(offset uint64) (float64, error) {
+	v, err := l.readUint(offset, 8, binary.LittleEndian)
+	return math.Float64frombits(v), err
+}
+
+// ReadF64BE reads a big-endian 64-bit float at offset
+func (l *LuaBuffer) ReadF64BE(offset uint64) (float64, error) {
+	v, err := l.readUint(offset, 8, binary.BigEndian)
+	return math.Float64frombits(v), err
+}
+
+// ReadString reads length bytes at offset as a string (like buffer.readstring)
+func (l *LuaBuffer) ReadString(offset, length uint64) (string, error) {
+	if length > math.MaxInt32 {
+		return "", fmt.Errorf("string length %d is too large", length)
+	}
+	var s string
+	err := l.access(offset, int(length), func(b []byte) {
+		s = string(b)
+	})
+	return s, err
+}
+
+// WriteU8 writes an unsigned 8-bit integer at offset (like buffer.writeu8)
+func (l *LuaBuffer) WriteU8(offset uint64, v uint8) error {
+	return l.writeUint(offset, 1, binary.LittleEndian, uint64(v))
+}
+
+// WriteI8 writes a signed 8-bit integer at offset (like buffer.writei8)
+func (l *LuaBuffer) WriteI8(offset uint64, v int8) error {
+	return l.writeUint(offset, 1, binary.LittleEndian, uint64(uint8(v)))
+}
+
+// WriteU16 writes a little-endian unsigned 16-bit integer at offset (like buffer.writeu16)
+func (l *LuaBuffer) WriteU16(offset uint64, v uint16) error {
+	return l.writeUint(offset, 2, binary.LittleEndian, uint64(v))
+}
+
+// WriteU16BE writes a big-endian unsigned 16-bit integer at offset
+func (l *LuaBuffer) WriteU16BE(offset uint64, v uint16) error {
+	return l.writeUint(offset, 2, binary.BigEndian, uint64(v))
+}
+
+// WriteI16 writes a little-endian signed 16-bit integer at offset (like buffer.writei16)
+func (l *LuaBuffer) WriteI16(offset uint64, v int16) error {
+	return l.writeUint(offset, 2, binary.LittleEndian, uint64(uint16(v)))
+}
+
+// WriteI16BE writes a big-endian signed 16-bit integer at offset
+func (l *LuaBuffer) WriteI16BE(offset uint64, v int16) error {
+	return l.writeUint(offset, 2, binary.BigEndian, uint64(uint16(v)))
+}
+
+// WriteU32 writes a little-endian unsigned 32-bit integer at offset (like buffer.writeu32)
+func (l *LuaBuffer) WriteU32(offset uint64, v uint32) error {
+	return l.writeUint(offset, 4, binary.LittleEndian, uint64(v))
+}
+
+// WriteU32BE writes a big-endian unsigned 32-bit integer at offset
+func (l *LuaBuffer) WriteU32BE(offset uint64, v uint32) error {
+	return l.writeUint(offset, 4, binary.BigEndian, uint64(v))
+}
+
+// WriteI32 writes a little-endian signed 32-bit integer at offset (like buffer.writei32)
+func (l *LuaBuffer) WriteI32(offset uint64, v int32) error {
+	return l.writeUint(offset, 4, binary.LittleEndian, uint64(uint32(v)))
+}
+
+// WriteI32BE writes a big-endian signed 32-bit integer at offset
+func (l *LuaBuffer) WriteI32BE(offset uint64, v int32) error {
+	return l.writeUint(offset, 4, binary.BigEndian, uint64(uint32(v)))
+}
+
+// WriteF32 writes a little-endian 32-bit float at offset (like buffer.writef32)
+func (l *LuaBuffer) WriteF32(offset uint64, v float32) error {
+	return l.writeUint(offset, 4, binary.LittleEndian, uint64(math.Float32bits(v)))
+}
+
+// WriteF32BE writes a big-endian 32-bit float at offset
+func (l *LuaBuffer) WriteF32BE(offset uint64, v float32) error {
+	return l.writeUint(offset, 4, binary.BigEndian, uint64(math.Float32bits(v)))
+}
+
+// WriteF64 writes a little-endian 64-bit float at offset (like buffer.writef64)
+func (l *LuaBuffer) WriteF64(offset uint64, v float64) error {
+	return l.writeUint(offset, 8, binary.LittleEndian, math.Float64bits(v))
+}
+
+// WriteF64BE writes a big-endian 64-bit float at offset
+func (l *LuaBuffer) WriteF64BE(offset uint64, v float64) error {
+	return l.writeUint(offset, 8, binary.BigEndian, math.Float64bits(v))
+}
+
+// WriteString writes the bytes of s at offset (like buffer.writestring)
+func (l *LuaBuffer) WriteString(offset uint64, s string) error {
+	return l.access(offset, len(s), func(b []byte) {
+		copy(b, s)
+	})
+}
+
+// ReadAt implements io.ReaderAt
+func (l *LuaBuffer) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("LuaBuffer.ReadAt: negative offset")
+	}
+	var n int
+	err := l.WithBytes(func(b []byte) error {
+		if off >= int64(len(b)) {
+			return io.EOF
+		}
+		n = copy(p, b[off:])
+		if n < len(p) {
+			return io.EOF
+		}
+		return nil
+	})
+	return n, err
+}
+
+// WriteAt implements io.WriterAt
+//
+// As buffers have a fixed size, writing past the end of the buffer writes as much as
+// fits and returns io.ErrShortWrite.
+func (l *LuaBuffer) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("LuaBuffer.WriteAt: negative offset")
+	}
+	var n int
+	err := l.WithBytes(func(b []byte) error {
+		if off < int64(len(b)) {
+			n = copy(b[off:], p)
+		}
+		if n < len(p) {
+			return io.ErrShortWrite
+		}
+		return nil
+	})
+	return n, err
+}
+
+// A BufferCursor reads and writes a LuaBuffer sequentially, implementing io.ReadWriteSeeker
+//
+// Like the buffer itself, a BufferCursor should not be used concurrently.
+type BufferCursor struct {
+	buf *LuaBuffer
+	pos int64
+}
+
+// Cursor returns a new BufferCursor positioned at the start of the buffer
+func (l *LuaBuffer) Cursor() *BufferCursor {
+	return &BufferCursor{buf: l}
+}
+
+// Buffer returns the buffer of the cursor
+func (c *BufferCursor) Buffer() *LuaBuffer {
+	return c.buf
+}
+
+// Pos returns the current position of the cursor
+func (c *BufferCursor) Pos() int64 {
+	return c.pos
+}
+
+// Read implements io.Reader
+func (c *BufferCursor) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n, err := c.buf.ReadAt(p, c.pos)
+	c.pos += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil // io.Reader reports EOF on the next call
+	}
+	return n, err
+}
+
+// Write implements io.Writer, writing past the end of the buffer returns io.ErrShortWrite
+func (c *BufferCursor) Write(p []byte) (int, error) {
+	n, err := c.buf.WriteAt(p, c.pos)
+	c.pos += int64(n)
+	return n, err
+}
+
+// Seek implements io.Seeker. Seeking past the end of the buffer is allowed, but reads
+// there return io.EOF and writes fail.
+func (c *BufferCursor) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = c.pos + offset
+	case io.SeekEnd:
+		pos = int64(c.buf.Len()) + offset
+	default:
+		return c.pos, errors.New("BufferCursor.Seek: invalid whence")
+	}
+	if pos < 0 {
+		return c.pos, errors.New("BufferCursor.Seek: negative position")
+	}
+	c.pos = pos
+	return pos, nil
+}
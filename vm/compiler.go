@@ -4,6 +4,7 @@ package vm
 #include "../rustlib/rustlib.h"
 */
 import "C"
+import "unsafe"
 
 type OptimizationLevel int
 
@@ -70,3 +71,38 @@ func (opts *CompilerOpts) toC() C.struct_CompilerOpts {
 		coverage_level:     C.uint8_t(opts.CoverageLevel),
 	}
 }
+
+// Compile compiles Luau source code to bytecode, which can be loaded using ChunkModeBinary
+//
+// If opts is nil, the default compiler options are used.
+func (l *Lua) Compile(code string, opts *CompilerOpts) ([]byte, error) {
+	bytecode, err := l.compile(code, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer bytecode.Close()
+	return bytecode.Bytes(), nil
+}
+
+func (l *Lua) compile(code string, opts *CompilerOpts) (*LuaString, error) {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &CompilerOpts{OptimizationLevel: OptimizationLevelBasic, DebugLevel: DebugLevelLineInfo}
+	}
+	var src *C.char
+	if len(code) > 0 {
+		src = (*C.char)(unsafe.Pointer(unsafe.StringData(code)))
+	}
+	res := C.luago_compile(lua, src, C.size_t(len(code)), opts.toC())
+	if res.error != nil {
+		return nil, moveErrorToGo(res.error)
+	}
+	return &LuaString{object: l.newObject((*C.void)(unsafe.Pointer(res.value)), stringTab), lua: l}, nil
+}
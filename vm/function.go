@@ -10,6 +10,7 @@ import (
 )
 
 var functionTab = objectTab{
+	name: "LuaFunction",
 	dtor: func(ptr *C.void) {
 		C.luago_free_function((*C.struct_LuaFunction)(unsafe.Pointer(ptr)))
 	},
@@ -17,7 +18,7 @@ var functionTab = objectTab{
 
 // A LuaFunction is an wrapper around a function
 //
-// API's to be implemented as of now: coverage, info (more complex to implement),
+// API's to be implemented as of now: coverage
 type LuaFunction struct {
 	object *object
 	lua    *Lua
@@ -34,6 +35,9 @@ func (l *LuaFunction) innerPtr() (*C.struct_LuaFunction, error) {
 // Call calls a function `f` returning either the returned arguments
 // or the error
 //
+// If traceback capture is enabled on the Lua VM (see Lua.SetTracebackOnError),
+// this behaves like CallWithTraceback.
+//
 // Locking behavior: This function acquires a read lock on the LuaFunction object
 // and a write lock on all arguments passed to the function.
 func (l *LuaFunction) Call(args ...Value) ([]Value, error) {
@@ -41,6 +45,14 @@ func (l *LuaFunction) Call(args ...Value) ([]Value, error) {
 		return nil, fmt.Errorf("cannot call function on closed Lua VM")
 	}
 
+	if err := l.lua.checkNoView(); err != nil {
+		return nil, err
+	}
+
+	if l.lua.tracebackOnError.Load() {
+		return l.CallWithTraceback(args...)
+	}
+
 	l.object.RLock()
 	defer l.object.RUnlock()
 
@@ -53,9 +65,60 @@ func (l *LuaFunction) Call(args ...Value) ([]Value, error) {
 		return nil, err // Return error if the value cannot be converted
 	}
 
+	l.lua.clearPendingError()
 	res := C.luago_function_call(ptr, mw.ptr)
 	if res.error != nil {
-		return nil, moveErrorToGo(res.error)
+		return nil, l.lua.wrapCallError(moveErrorToGo(res.error))
+	}
+	rets := &luaMultiValue{ptr: res.value, lua: l.lua}
+	retsMw := rets.take()
+	rets.close()
+	return retsMw, nil
+}
+
+// CallWithTraceback calls a function `f` like Call, but with a message handler
+// installed that captures the stack at the point of the error (similar to
+// `xpcall(f, debug.traceback)`).
+//
+// On error, a *TracebackError is returned. The captured frames include
+// frames of Go functions that the error passed through. This does not
+// need the debug library to be loaded into the Lua VM.
+//
+// Locking behavior: This function acquires a read lock on the LuaFunction object
+// and a write lock on all arguments passed to the function. Like Call, it does not
+// hold a lock on the Lua VM while the function runs.
+func (l *LuaFunction) CallWithTraceback(args ...Value) ([]Value, error) {
+	if l.lua.object.IsClosed() {
+		return nil, fmt.Errorf("cannot call function on closed Lua VM")
+	}
+
+	lua, err := l.lua.cloneHandle()
+	if err != nil {
+		return nil, err // Return error if the Lua VM is closed
+	}
+	defer C.freeluavm(lua)
+
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	ptr, err := l.innerPtr()
+	if err != nil {
+		return nil, err // Return error if the object is closed
+	}
+	mw, err := l.lua.multiValueFromValues(args)
+	if err != nil {
+		return nil, err // Return error if the value cannot be converted
+	}
+
+	l.lua.clearPendingError()
+	res := C.luago_function_call_traceback(lua, ptr, mw.ptr)
+	if res.error != nil {
+		msg := moveStringToGo(res.error)
+		return nil, &TracebackError{
+			Message: msg,
+			Frames:  moveStackFramesToGo(res.frames, res.nframes),
+			cause:   l.lua.takePendingError(msg),
+		}
 	}
 	rets := &luaMultiValue{ptr: res.value, lua: l.lua}
 	retsMw := rets.take()
@@ -88,7 +151,7 @@ func (l *LuaFunction) DeepClone() (*LuaFunction, error) {
 		return nil, err
 	}
 
-	return &LuaFunction{object: newObject((*C.void)(unsafe.Pointer(res.value)), functionTab), lua: l.lua}, nil
+	return &LuaFunction{object: l.lua.newObject((*C.void)(unsafe.Pointer(res.value)), functionTab), lua: l.lua}, nil
 }
 
 // Returns the environment table of the LuaFunction.
@@ -112,7 +175,7 @@ func (l *LuaFunction) Environment() (*LuaTable, error) {
 		return nil, nil // No environment table
 	}
 
-	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(tab)), tableTab), lua: l.lua}, nil
+	return &LuaTable{object: l.lua.newObject((*C.void)(unsafe.Pointer(tab)), tableTab), lua: l.lua}, nil
 }
 
 // Sets the environment table of the LuaFunction returning true if the environment was set
@@ -150,6 +213,83 @@ func (l *LuaFunction) SetEnvironment(env *LuaTable) (bool, error) {
 	return bool(res.value), nil
 }
 
+// FunctionInfo contains debug information about a LuaFunction
+type FunctionInfo struct {
+	// The debug name of the function, if known
+	//
+	// For Luau functions, this is the name the function was declared with
+	// (e.g. "foo" for `local function foo() end`)
+	Name string
+	// What kind of function this is ("Lua" for Luau functions, "C" for Go functions
+	// and "main" for the main chunk)
+	What string
+	// The source of the function (the chunk name it was loaded with)
+	Source string
+	// A "printable" version of Source for use in error messages
+	ShortSource string
+	// The line the function was defined on, or -1 if not known
+	// (e.g. for Go functions)
+	LineDefined int
+	// The line the function definition ends on, or -1 if not known
+	//
+	// Luau does not record where a function definition ends so this
+	// is currently always -1
+	LastLineDefined int
+	// The number of fixed parameters the function takes
+	NumParams int
+	// Whether the function accepts a variable number of arguments (...)
+	IsVararg bool
+	// The number of upvalues the function captures
+	NumUpvalues int
+}
+
+// IsGoFunction returns true if the function is implemented in Go (or C)
+// and not in Luau
+func (f *FunctionInfo) IsGoFunction() bool {
+	return f.What == "C"
+}
+
+// Info returns debug information about the function
+//
+// This works regardless of whether the debug library is loaded in the VM
+func (l *LuaFunction) Info() (*FunctionInfo, error) {
+	if l.lua.object.IsClosed() {
+		return nil, fmt.Errorf("cannot get info of function on closed Lua VM")
+	}
+
+	l.lua.object.RLock()
+	defer l.lua.object.RUnlock()
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua.lua()
+	if err != nil {
+		return nil, err // Return error if the Lua VM is closed
+	}
+	ptr, err := l.innerPtr()
+	if err != nil {
+		return nil, err // Return error if the object is closed
+	}
+
+	res := C.luago_function_info(lua, ptr)
+	// Take ownership of all strings first so they are always freed
+	info := &FunctionInfo{
+		Name:            moveStringToGo(res.name),
+		What:            moveStringToGo(res.what),
+		Source:          moveStringToGo(res.source),
+		ShortSource:     moveStringToGo(res.short_src),
+		LineDefined:     int(res.line_defined),
+		LastLineDefined: -1,
+		NumParams:       int(res.num_params),
+		IsVararg:        bool(res.is_vararg),
+		NumUpvalues:     int(res.num_upvalues),
+	}
+	if res.error != nil {
+		return nil, moveErrorToGo(res.error)
+	}
+	return info, nil
+}
+
 // Returns a 'pointer' to a Lua-owned function
 //
 // This pointer is only useful for hashing/debugging
@@ -0,0 +1,217 @@
+package vm
+
+/*
+#include "../rustlib/rustlib.h"
+*/
+import "C"
+import "fmt"
+
+// The number of memory categories supported by Luau
+const MemoryCategoryCount = 256
+
+// GCCollect performs a full garbage collection cycle
+func (l *Lua) GCCollect() error {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return err
+	}
+
+	res := C.luago_gc_collect(lua)
+	if res.error != nil {
+		return moveErrorToGo(res.error)
+	}
+	l.manualGCs.Add(1)
+	return nil
+}
+
+// GCStep performs an incremental garbage collection step doing (roughly)
+// kbytes kilobytes worth of work.
+//
+// This is useful to schedule garbage collection work at predictable points
+// (e.g. between frames). Returns true if the step finished a collection cycle.
+func (l *Lua) GCStep(kbytes int) (bool, error) {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return false, err
+	}
+
+	res := C.luago_gc_step(lua, C.int32_t(kbytes))
+	if res.error != nil {
+		return false, moveErrorToGo(res.error)
+	}
+	finished := bool(res.value)
+	if finished {
+		l.manualGCs.Add(1)
+	}
+	return finished, nil
+}
+
+// GCStop stops the garbage collector from running automatically
+//
+// Collection can still be triggered manually using GCCollect and GCStep
+func (l *Lua) GCStop() {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return // No-op if the Lua VM is closed
+	}
+
+	C.luago_gc_stop(lua)
+}
+
+// GCRestart restarts the garbage collector after a call to GCStop
+func (l *Lua) GCRestart() {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return // No-op if the Lua VM is closed
+	}
+
+	C.luago_gc_restart(lua)
+}
+
+// GCIsRunning returns true if the garbage collector is running automatically
+// (e.g. is not stopped)
+func (l *Lua) GCIsRunning() bool {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return false // Return false if the Lua VM is closed
+	}
+
+	return bool(C.luago_gc_is_running(lua))
+}
+
+// GCTuning contains the tuning parameters of Luau's incremental garbage collector
+//
+// A value of 0 leaves the corresponding parameter unchanged
+type GCTuning struct {
+	// The heap size the GC aims to reach before starting a new cycle as a
+	// percentage of the live heap size after the previous cycle (Luau's default is 200)
+	Goal int
+	// How much work the GC does per step relative to the allocation rate as a percentage
+	// (Luau's default is 200)
+	StepMultiplier int
+	// The amount of allocation in kilobytes between GC steps (Luau's default is 1)
+	StepSize int
+}
+
+// SetGCTuning sets the tuning parameters of the garbage collector
+// returning the previous parameters.
+func (l *Lua) SetGCTuning(tuning GCTuning) (GCTuning, error) {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return GCTuning{}, err
+	}
+
+	cTuning := C.struct_GcTuning{
+		goal:            C.int32_t(tuning.Goal),
+		step_multiplier: C.int32_t(tuning.StepMultiplier),
+		step_size:       C.int32_t(tuning.StepSize),
+	}
+	var prev C.struct_GcTuning
+	res := C.luago_gc_set_tuning(lua, cTuning, &prev)
+	if res.error != nil {
+		return GCTuning{}, moveErrorToGo(res.error)
+	}
+	return GCTuning{
+		Goal:           int(prev.goal),
+		StepMultiplier: int(prev.step_multiplier),
+		StepSize:       int(prev.step_size),
+	}, nil
+}
+
+// GCState is a phase of a garbage collection cycle
+type GCState int
+
+const (
+	GCStatePause          GCState = 0 // Waiting for the next cycle to start
+	GCStatePropagate      GCState = 1 // Marking reachable objects
+	GCStatePropagateAgain GCState = 2 // Marking objects changed while propagating
+	GCStateAtomic         GCState = 3 // Finishing marking in a single step
+	GCStateSweep          GCState = 4 // Freeing unreachable objects
+)
+
+// Returns the name of the GC state
+func (s GCState) String() string {
+	switch s {
+	case GCStatePause:
+		return "pause"
+	case GCStatePropagate:
+		return "propagate"
+	case GCStatePropagateAgain:
+		return "propagateagain"
+	case GCStateAtomic:
+		return "atomic"
+	case GCStateSweep:
+		return "sweep"
+	default:
+		return fmt.Sprintf("GCState(%d)", int(s))
+	}
+}
+
+// GCStats contains statistics about the garbage collector and memory usage of the Lua VM
+type GCStats struct {
+	// The total size of the heap in bytes
+	HeapSize int
+	// Whether the garbage collector is running automatically
+	Running bool
+	// The state the garbage collector was in during its last step (GCStatePause if it
+	// has not run yet)
+	//
+	// Luau only reports the state after each step, so a cycle finished by the last step
+	// is reported by the state of that step rather than GCStatePause.
+	State GCState
+	// The number of collection cycles completed through GCCollect and GCStep
+	//
+	// Luau does not expose the number of cycles completed automatically so those are
+	// not counted here
+	ManualCollections uint64
+	// The number of bytes allocated in each memory category, indexed by category.
+	//
+	// Category 0 is the default category all allocations go to unless changed
+	CategoryUsage [MemoryCategoryCount]int
+}
+
+// GCStats returns statistics about the garbage collector
+func (l *Lua) GCStats() (*GCStats, error) {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &GCStats{
+		HeapSize:          int(C.luago_used_memory(lua)),
+		Running:           bool(C.luago_gc_is_running(lua)),
+		State:             GCState(C.luago_gc_state(lua)),
+		ManualCollections: l.manualGCs.Load(),
+	}
+
+	var usage [MemoryCategoryCount]C.size_t
+	res := C.luago_memory_category_usage(lua, &usage[0], C.size_t(len(usage)))
+	if res.error != nil {
+		return nil, moveErrorToGo(res.error)
+	}
+	for i, u := range usage {
+		stats.CategoryUsage[i] = int(u)
+	}
+	return stats, nil
+}
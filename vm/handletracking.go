@@ -0,0 +1,228 @@
+package vm
+
+import "C"
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// A TrackedHandle is a live Go-side handle to a Luau object recorded
+// while handle tracking is enabled (see Lua.EnableHandleTracking)
+type TrackedHandle struct {
+	// The type of the handle (e.g. "LuaTable")
+	Type string
+	// When the handle was created
+	Created time.Time
+	// The stack trace of where the handle was created
+	Stack string
+}
+
+func (h TrackedHandle) String() string {
+	return fmt.Sprintf("%s created at %s\n%s", h.Type, h.Created.Format(time.RFC3339Nano), h.Stack)
+}
+
+type trackedHandle struct {
+	typ     string
+	created time.Time
+	pcs     []uintptr
+}
+
+func (h *trackedHandle) toTrackedHandle() TrackedHandle {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(h.pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&sb, "\t%s\n\t\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return TrackedHandle{Type: h.typ, Created: h.created, Stack: sb.String()}
+}
+
+type handleTracker struct {
+	sync.Mutex
+	// Keyed by the address of the object so tracking does not keep
+	// the object alive (and prevent its finalizer from running)
+	handles map[uintptr]*trackedHandle
+	onLeak  func(leaked []TrackedHandle)
+	// Handles that were closed by the garbage collector instead of being closed
+	collected []*trackedHandle
+}
+
+func (t *handleTracker) track(o *object) {
+	// Skip runtime.Callers, track, Lua.newObject
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+
+	t.Lock()
+	defer t.Unlock()
+	o.tracker = t
+	t.handles[uintptr(unsafe.Pointer(o))] = &trackedHandle{
+		typ:     o.tab.name,
+		created: time.Now(),
+		pcs:     pcs[:n],
+	}
+}
+
+func (t *handleTracker) untrack(o *object) {
+	if t == nil {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	delete(t.handles, uintptr(unsafe.Pointer(o)))
+}
+
+// Records that an object is being closed by the garbage collector
+func (t *handleTracker) leak(o *object) {
+	if t == nil {
+		return
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	key := uintptr(unsafe.Pointer(o))
+	if h, ok := t.handles[key]; ok {
+		delete(t.handles, key)
+		t.collected = append(t.collected, h)
+	}
+}
+
+func (t *handleTracker) live() []TrackedHandle {
+	t.Lock()
+	handles := make([]*trackedHandle, 0, len(t.handles))
+	for _, h := range t.handles {
+		handles = append(handles, h)
+	}
+	t.Unlock()
+	return toTrackedHandles(handles)
+}
+
+// Returns the live handles along with the handles closed by the garbage collector
+func (t *handleTracker) leaked() []TrackedHandle {
+	t.Lock()
+	handles := make([]*trackedHandle, 0, len(t.handles)+len(t.collected))
+	for _, h := range t.handles {
+		handles = append(handles, h)
+	}
+	handles = append(handles, t.collected...)
+	t.collected = nil
+	t.Unlock()
+	return toTrackedHandles(handles)
+}
+
+// Converts handles to TrackedHandles ordered by creation time
+func toTrackedHandles(handles []*trackedHandle) []TrackedHandle {
+	sort.Slice(handles, func(i, j int) bool {
+		return handles[i].created.Before(handles[j].created)
+	})
+	live := make([]TrackedHandle, len(handles))
+	for i, h := range handles {
+		live[i] = h.toTrackedHandle()
+	}
+	return live
+}
+
+// Reports leaked handles to stderr
+func reportLeakedHandles(leaked []TrackedHandle) {
+	fmt.Fprintf(os.Stderr, "gluau: %d Luau handle(s) were not closed before the Lua VM was closed\n", len(leaked))
+	for _, h := range leaked {
+		fmt.Fprintln(os.Stderr, h)
+	}
+}
+
+// Creates a new object owned by this Lua VM, tracking it if handle tracking is enabled
+func (l *Lua) newObject(ptr *C.void, tab objectTab) *object {
+	obj := newObject(ptr, tab)
+	if obj == nil || l == nil {
+		return obj
+	}
+	if t := l.handles.Load(); t != nil {
+		t.track(obj)
+	}
+	return obj
+}
+
+// EnableHandleTracking enables tracking of all Go-side handles (LuaTable, LuaString,
+// LuaFunction etc.) to Luau objects created from now on. This is a debugging aid
+// for finding handles that are never closed and keep Luau objects alive.
+//
+// For every live handle, the stack trace of where it was created is recorded. When
+// the Lua VM is closed, onLeak is called with all handles that were not closed,
+// including handles which were only closed when Go garbage collected them (if onLeak
+// is nil, leaked handles are printed to stderr). Handles created from callbacks are
+// tracked as well.
+//
+// Handle tracking has a significant performance cost and should not be used in production.
+// Building with the `gluau_handletracking` build tag enables handle tracking on all
+// Lua VMs by default.
+func (l *Lua) EnableHandleTracking(onLeak func(leaked []TrackedHandle)) {
+	if onLeak == nil {
+		onLeak = reportLeakedHandles
+	}
+	if t := l.handles.Load(); t != nil {
+		t.Lock()
+		t.onLeak = onLeak
+		t.Unlock()
+		return
+	}
+	l.handles.CompareAndSwap(nil, &handleTracker{
+		handles: make(map[uintptr]*trackedHandle),
+		onLeak:  onLeak,
+	})
+}
+
+// LiveHandles returns all live (unclosed) handles created while handle tracking was enabled,
+// ordered by creation time.
+//
+// Returns nil if handle tracking is not enabled.
+func (l *Lua) LiveHandles() []TrackedHandle {
+	t := l.handles.Load()
+	if t == nil {
+		return nil
+	}
+	return t.live()
+}
+
+// HandleCounts returns the number of live (unclosed) handles by type
+//
+// Returns nil if handle tracking is not enabled.
+func (l *Lua) HandleCounts() map[string]int {
+	t := l.handles.Load()
+	if t == nil {
+		return nil
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	counts := make(map[string]int)
+	for _, h := range t.handles {
+		counts[h.typ]++
+	}
+	return counts
+}
+
+// Reports all live handles as leaked (called when the Lua VM is closed)
+func (l *Lua) reportLeakedHandles() {
+	t := l.handles.Load()
+	if t == nil {
+		return
+	}
+
+	leaked := t.leaked()
+	if len(leaked) == 0 {
+		return
+	}
+	t.Lock()
+	onLeak := t.onLeak
+	t.Unlock()
+	onLeak(leaked)
+}
@@ -0,0 +1,6 @@
+//go:build !gluau_handletracking
+
+package vm
+
+// Whether handle tracking is enabled on new Lua VMs by default
+const trackHandlesByDefault = false
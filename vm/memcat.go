@@ -0,0 +1,259 @@
+package vm
+
+/*
+#include "../rustlib/rustlib.h"
+*/
+import "C"
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The name of the default memory category (0) all allocations are attributed to
+// unless a thread's memory category is changed
+const MainMemoryCategory = "main"
+
+// Go-side state of the memory categories of a Lua VM
+type memoryCategories struct {
+	sync.RWMutex
+	names  map[string]int
+	byID   [MemoryCategoryCount]string
+	limits map[int]int
+}
+
+func (m *memoryCategories) name(id int) string {
+	if id == 0 {
+		return MainMemoryCategory
+	}
+	if name := m.byID[id]; name != "" {
+		return name
+	}
+	return strconv.Itoa(id)
+}
+
+func (m *memoryCategories) hasLimits() bool {
+	m.RLock()
+	defer m.RUnlock()
+	return len(m.limits) > 0
+}
+
+// A MemoryCategoryLimitError is returned when a memory category exceeds
+// the soft limit set using Lua.SetMemoryCategoryLimit
+type MemoryCategoryLimitError struct {
+	Category string // The name of the memory category
+	ID       int    // The id of the memory category
+	Limit    int    // The soft limit of the memory category in bytes
+	Usage    int    // The number of bytes allocated in the memory category
+}
+
+func (e *MemoryCategoryLimitError) Error() string {
+	return fmt.Sprintf("memory category '%s' exceeded its limit (%d bytes used, limit is %d bytes)", e.Category, e.Usage, e.Limit)
+}
+
+// RegisterMemoryCategory registers a named memory category returning its id
+//
+// Registering an already registered name returns the existing id. The "main"
+// category always has id 0. At most 255 categories can be registered.
+func (l *Lua) RegisterMemoryCategory(name string) (int, error) {
+	if name == "" {
+		return 0, fmt.Errorf("memory category name cannot be empty")
+	}
+	if name == MainMemoryCategory {
+		return 0, nil
+	}
+
+	l.memCategories.Lock()
+	defer l.memCategories.Unlock()
+
+	if id, ok := l.memCategories.names[name]; ok {
+		return id, nil
+	}
+
+	for id := 1; id < MemoryCategoryCount; id++ {
+		if l.memCategories.byID[id] == "" {
+			if l.memCategories.names == nil {
+				l.memCategories.names = make(map[string]int)
+			}
+			l.memCategories.names[name] = id
+			l.memCategories.byID[id] = name
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot register memory category '%s': all memory categories are in use", name)
+}
+
+// MemoryCategory returns the id of a registered memory category
+func (l *Lua) MemoryCategory(name string) (int, bool) {
+	if name == MainMemoryCategory {
+		return 0, true
+	}
+
+	l.memCategories.RLock()
+	defer l.memCategories.RUnlock()
+	id, ok := l.memCategories.names[name]
+	return id, ok
+}
+
+// MemoryByCategory returns the number of bytes allocated per memory category
+//
+// Registered categories (and the "main" category) are always included. Unregistered
+// categories are only included (keyed by their id) if they have memory allocated to them.
+func (l *Lua) MemoryByCategory() map[string]int {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return nil // Return nil if the Lua VM is closed
+	}
+
+	var usage [MemoryCategoryCount]C.size_t
+	res := C.luago_memory_category_usage(lua, &usage[0], C.size_t(len(usage)))
+	if res.error != nil {
+		moveErrorToGo(res.error) // Free the error
+		return nil
+	}
+
+	l.memCategories.RLock()
+	defer l.memCategories.RUnlock()
+
+	byCategory := make(map[string]int)
+	for id, u := range usage {
+		if u == 0 && id != 0 && l.memCategories.byID[id] == "" {
+			continue
+		}
+		byCategory[l.memCategories.name(id)] = int(u)
+	}
+	return byCategory
+}
+
+// SetMemoryCategoryLimit sets a soft limit (in bytes) on a memory category.
+// A limit of 0 or less removes the limit.
+//
+// Unlike SetMemoryLimit, soft limits are checked periodically (using the same
+// mechanism as SetInterrupt) and not on every allocation. When a memory category is
+// found to exceed its limit, the running Luau code errors and the error returned to
+// Go wraps a *MemoryCategoryLimitError (use errors.As to check for it).
+func (l *Lua) SetMemoryCategoryLimit(id int, limit int) error {
+	if id < 0 || id >= MemoryCategoryCount {
+		return fmt.Errorf("invalid memory category %d", id)
+	}
+
+	l.memCategories.Lock()
+	if limit <= 0 {
+		delete(l.memCategories.limits, id)
+	} else {
+		if l.memCategories.limits == nil {
+			l.memCategories.limits = make(map[int]int)
+		}
+		l.memCategories.limits[id] = limit
+	}
+	l.memCategories.Unlock()
+
+	l.interruptMu.Lock()
+	defer l.interruptMu.Unlock()
+	return l.updateInterrupt()
+}
+
+// CheckMemoryCategoryLimits returns a *MemoryCategoryLimitError for the first
+// memory category (by id) that exceeds its soft limit, or nil if no limit is exceeded
+func (l *Lua) CheckMemoryCategoryLimits() error {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return err
+	}
+
+	l.memCategories.RLock()
+	defer l.memCategories.RUnlock()
+
+	ids := make([]int, 0, len(l.memCategories.limits))
+	for id := range l.memCategories.limits {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		limit := l.memCategories.limits[id]
+		usage := int(C.luago_memory_category_bytes(lua, C.uint8_t(id)))
+		if usage > limit {
+			return &MemoryCategoryLimitError{
+				Category: l.memCategories.name(id),
+				ID:       id,
+				Limit:    limit,
+				Usage:    usage,
+			}
+		}
+	}
+	return nil
+}
+
+// An error returned from Luau that was caused by a typed error raised by gluau
+type causedError struct {
+	err   error
+	cause error
+}
+
+func (e *causedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *causedError) Unwrap() error {
+	return e.cause
+}
+
+// Clears the typed error of an earlier call, so that a limit error caught by the script
+// (e.g. using pcall) is not attached to the error of a later, unrelated call
+func (l *Lua) clearPendingError() {
+	l.pendingLimitErr.Store(nil)
+}
+
+// Returns (and clears) the typed error that caused a call to fail with the error message
+// msg (if any)
+//
+// The typed error is only returned if msg contains it, as the script may have caught it and
+// failed for another reason.
+func (l *Lua) takePendingError(msg string) error {
+	limitErr := l.pendingLimitErr.Swap(nil)
+	if limitErr == nil || !strings.Contains(msg, limitErr.Error()) {
+		return nil
+	}
+	return limitErr
+}
+
+// Attaches the typed error that caused a call to fail (if any) to the error
+// returned by the call
+func (l *Lua) wrapCallError(err error) error {
+	cause := l.takePendingError(err.Error())
+	if cause == nil {
+		return err
+	}
+	return &causedError{err: err, cause: cause}
+}
+
+// SetMemoryCategory sets the memory category new allocations made by the thread are attributed to
+//
+// See Lua.RegisterMemoryCategory to register named categories.
+//
+// Locking behavior: This function acquires a read lock on the LuaThread object.
+func (l *LuaThread) SetMemoryCategory(id int) error {
+	if id < 0 || id >= MemoryCategoryCount {
+		return fmt.Errorf("invalid memory category %d", id)
+	}
+
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	ptr, err := l.innerPtr()
+	if err != nil {
+		return err // Return error if the object is closed
+	}
+
+	C.luago_thread_set_memory_category(ptr, C.uint8_t(id))
+	return nil
+}
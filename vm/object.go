@@ -8,6 +8,8 @@ import (
 import "C"
 
 type objectTab struct {
+	// name is the name of the type of the object (used for debugging)
+	name string
 	// dtor is the destructor function for the object
 	// called on Close() or when finalizer is called
 	dtor func(ptr *C.void)
@@ -19,6 +21,7 @@ type object struct {
 	ptr          *C.void
 	closed       bool
 	tab          objectTab
+	tracker      *handleTracker // Set if handle tracking is enabled
 }
 
 // NewObject creates a Object from a C pointer.
@@ -28,10 +31,17 @@ func newObject(ptr *C.void, tab objectTab) *object {
 	}
 
 	obj := &object{ptr: ptr, tab: tab}
-	runtime.SetFinalizer(obj, (*object).Close) // Set finalizer to clean up LuaString
+	runtime.SetFinalizer(obj, (*object).finalize) // Set finalizer to clean up LuaString
 	return obj
 }
 
+// Closes an object that was garbage collected without being closed, which handle
+// tracking reports as a leak
+func (o *object) finalize() {
+	o.tracker.leak(o)
+	o.Close()
+}
+
 // PointerLock returns the C pointer of the object after
 // acquiring a read lock. Use this when you need to ensure
 func (o *object) PointerLock() (*C.void, error) {
@@ -91,6 +101,7 @@ func (o *object) Close() error {
 	o.ptr = nil                  // Prevent double free
 	o.closed = true              // Mark as closed
 	runtime.SetFinalizer(o, nil) // Remove finalizer to prevent double calls
+	o.tracker.untrack(o)
 	return nil
 }
 
@@ -117,5 +128,6 @@ func (o *object) DisarmNoLock() error {
 
 	o.closed = true
 	runtime.SetFinalizer(o, nil) // Remove finalizer to prevent double calls
+	o.tracker.untrack(o)
 	return nil
 }
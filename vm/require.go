@@ -14,6 +14,7 @@ import (
 type NavigationResult struct {
 	ambiguous bool
 	notfound  bool
+	denied    bool // Denied results also set other to the reason
 	other     error
 }
 
@@ -27,25 +28,23 @@ func (n *NavigationResult) fillC(c *C.struct_GoNavigationResult) {
 	} else if n.notfound {
 		c.not_found = C.bool(true)
 	} else if n.other != nil {
+		c.denied = C.bool(n.denied)
 		c.other = moveStringToRust(n.other.Error())
 	}
 }
 
 // Returns a new ambiguous navigation result
 func AmbiguousNavigationResult() *NavigationResult {
-	fmt.Println("Creating ambiguous navigation result")
 	return &NavigationResult{ambiguous: true}
 }
 
 // Returns a new not found navigation result
 func NotFoundNavigationResult() *NavigationResult {
-	fmt.Println("Creating not found navigation result")
 	return &NavigationResult{notfound: true}
 }
 
 // Returns a new other navigation result with the given error
 func OtherNavigationResult(err error) *NavigationResult {
-	fmt.Println("Creating other navigation result with error:", err)
 	return &NavigationResult{other: err}
 }
 
@@ -54,6 +53,35 @@ func OtherStringNavigationResult(err string) *NavigationResult {
 	return &NavigationResult{other: errors.New(err)}
 }
 
+// Returns a new navigation result for a require denied by a policy, with the reason
+// as the error reported by require
+func DeniedNavigationResult(reason string) *NavigationResult {
+	return &NavigationResult{denied: true, other: errors.New("require denied: " + reason)}
+}
+
+// Returns whether the navigation result is an ambiguous result
+func (n *NavigationResult) IsAmbiguous() bool {
+	return n != nil && n.ambiguous
+}
+
+// Returns whether the navigation result is a not found result
+func (n *NavigationResult) IsNotFound() bool {
+	return n != nil && n.notfound
+}
+
+// Returns whether the navigation result is a denied result
+func (n *NavigationResult) IsDenied() bool {
+	return n != nil && n.denied
+}
+
+// Returns the error of an other or denied navigation result
+func (n *NavigationResult) Err() error {
+	if n == nil {
+		return nil
+	}
+	return n.other
+}
+
 // Require is the interface Luau Require will use
 // to resolve Luau module paths (Luau require-by-string).
 type Require interface {
@@ -113,19 +141,28 @@ func (l *Lua) CreateRequireFunction(require Require) (*LuaFunction, error) {
 	// Rust (or even C!) frames from Go, so we must recover() any panic
 	// that occurs in the callback to prevent a crash.
 
+	// The boolean and cache key callbacks cannot return errors to Luau. A panic in them is
+	// stored instead and returned by the next callback that can return an error, which is
+	// made to run by answering such that the require goes on (e.g. reporting a module).
+	var panicErr error
+	var panics int
+	takePanic := func() error {
+		err := panicErr
+		panicErr = nil
+		return err
+	}
+
 	isRequireAllowed := newGoCallback(func(val unsafe.Pointer) {
 		cval := (*C.struct_IsRequireAllowed)(val)
 		defer func() {
 			if r := recover(); r != nil {
-				fmt.Println("panic in isRequireAllowed callback:", r)
-				cval.data = false // Requires are not allowed if we panic
+				panicErr = fmt.Errorf("panic in IsRequireAllowed: %v", r)
+				cval.data = true // Reset returns the error
 			}
 		}()
 		chunkname := moveStringToGo(cval.chunk_name)
 		cval.data = C.bool(require.IsRequireAllowed(chunkname))
-	}, func() {
-		fmt.Println("isRequireAllowed is being dropped")
-	})
+	}, nil)
 
 	reset := newGoCallback(func(val unsafe.Pointer) {
 		cval := (*C.struct_ResetOrJumpToAliasOrToChild)(val)
@@ -136,117 +173,121 @@ func (l *Lua) CreateRequireFunction(require Require) (*LuaFunction, error) {
 			}
 		}()
 		chunkname := moveStringToGo(cval.str)
+		if err := takePanic(); err != nil {
+			OtherNavigationResult(err).fillC(&cval.data)
+			return
+		}
 		require.Reset(chunkname).fillC(&cval.data)
-	}, func() {
-		fmt.Println("reset is being dropped")
-	})
+	}, nil)
 
 	jumpToAlias := newGoCallback(func(val unsafe.Pointer) {
 		cval := (*C.struct_ResetOrJumpToAliasOrToChild)(val)
 		defer func() {
 			if r := recover(); r != nil {
-				ne := OtherNavigationResult(fmt.Errorf("panic in Reset: %v", r))
+				ne := OtherNavigationResult(fmt.Errorf("panic in JumpToAlias: %v", r))
 				ne.fillC(&cval.data)
 			}
 		}()
 		path := moveStringToGo(cval.str)
 		require.JumpToAlias(path).fillC(&cval.data)
-	}, func() {
-		fmt.Println("jumpToAlias is being dropped")
-	})
+	}, nil)
 
 	toParent := newGoCallback(func(val unsafe.Pointer) {
 		cval := (*C.struct_ToParent)(val)
 		defer func() {
 			if r := recover(); r != nil {
-				ne := OtherNavigationResult(fmt.Errorf("panic in Reset: %v", r))
+				ne := OtherNavigationResult(fmt.Errorf("panic in ToParent: %v", r))
 				ne.fillC(&cval.data)
 			}
 		}()
 		require.ToParent().fillC(&cval.data)
-	}, func() {
-		fmt.Println("toParent is being dropped")
-	})
+	}, nil)
 
 	toChild := newGoCallback(func(val unsafe.Pointer) {
 		cval := (*C.struct_ResetOrJumpToAliasOrToChild)(val)
 		defer func() {
 			if r := recover(); r != nil {
-				ne := OtherNavigationResult(fmt.Errorf("panic in Reset: %v", r))
+				ne := OtherNavigationResult(fmt.Errorf("panic in ToChild: %v", r))
 				ne.fillC(&cval.data)
 			}
 		}()
 		name := moveStringToGo(cval.str)
 		require.ToChild(name).fillC(&cval.data)
-	}, func() {
-		fmt.Println("toChild is being dropped")
-	})
+	}, nil)
 
 	hasModule := newGoCallback(func(val unsafe.Pointer) {
 		cval := (*C.struct_HasModuleOrHasConfig)(val)
 		defer func() {
 			if r := recover(); r != nil {
-				fmt.Println("panic in hasModule callback:", r)
-				cval.data = C.bool(false) // If we panic, we assume no module
+				panicErr = fmt.Errorf("panic in HasModule: %v", r)
+				cval.data = C.bool(true) // The loader returns the error
 			}
 		}()
 		cval.data = C.bool(require.HasModule())
-	}, func() {
-		fmt.Println("hasModule is being dropped")
-	})
+	}, nil)
 
 	cacheKey := newGoCallback(func(val unsafe.Pointer) {
 		cval := (*C.struct_CacheKey)(val)
 		defer func() {
 			if r := recover(); r != nil {
-				fmt.Println("panic in cacheKey callback:", r)
-				cval.data = moveStringToRust("")
+				if panicErr == nil {
+					panicErr = fmt.Errorf("panic in CacheKey: %v", r)
+				}
+				// A unique key makes sure the loader runs (and returns the error) instead of
+				// a cached module being returned
+				panics++
+				cval.data = moveStringToRust(fmt.Sprintf("\x00panic:%d", panics))
 			}
 		}()
+		if panicErr != nil {
+			panic(panicErr) // HasModule panicked, so the loader must run
+		}
 		cval.data = moveStringToRust(require.CacheKey())
-	}, func() {
-		fmt.Println("cacheKey is being dropped")
-	})
+	}, nil)
 
 	hasConfig := newGoCallback(func(val unsafe.Pointer) {
 		cval := (*C.struct_HasModuleOrHasConfig)(val)
 		defer func() {
 			if r := recover(); r != nil {
-				fmt.Println("panic in hasConfig callback:", r)
-				cval.data = C.bool(false) // If we panic, we assume no module
+				panicErr = fmt.Errorf("panic in HasConfig: %v", r)
+				cval.data = C.bool(true) // Config returns the error
 			}
 		}()
 		cval.data = C.bool(require.HasConfig())
-	}, func() {
-		fmt.Println("hasConfig is being dropped")
-	})
+	}, nil)
 
 	config := newGoCallback(func(val unsafe.Pointer) {
 		cval := (*C.struct_Config)(val)
 		defer func() {
 			if r := recover(); r != nil {
-				cval.error = moveStringToRust(fmt.Sprintf("panic in config callback: %v", r))
+				cval.error = moveStringToRust(fmt.Sprintf("panic in Config: %v", r))
 			}
 		}()
+		if err := takePanic(); err != nil {
+			cval.error = moveStringToRust(err.Error())
+			return
+		}
 		bytes, err := require.Config()
 		if err != nil {
 			cval.error = moveStringToRust(err.Error())
 			return
 		}
 		cval.data = moveBytesToRust(bytes)
-	}, func() {
-		fmt.Println("config is being dropped")
-	})
+	}, nil)
 
 	loader := newGoCallback(func(val unsafe.Pointer) {
 		cval := (*C.struct_Loader)(val)
 		defer func() {
 			if r := recover(); r != nil {
-				cval.error = moveStringToRust(fmt.Sprintf("panic in config callback: %v", r))
+				cval.error = moveStringToRust(fmt.Sprintf("panic in Loader: %v", r))
 			}
 		}()
+		if err := takePanic(); err != nil {
+			cval.error = moveStringToRust(err.Error())
+			return
+		}
 
-		callbackVm := &Lua{object: newObject((*C.void)(unsafe.Pointer(cval.lua)), luaVmTab)}
+		callbackVm := l.callbackLua(cval.lua)
 		defer callbackVm.Close() // Free the memory associated with the callback VM. TODO: Maybe switch to using a Deref API instead of Close?
 
 		cbLua := &CallbackLua{
@@ -275,9 +316,7 @@ func (l *Lua) CreateRequireFunction(require Require) (*LuaFunction, error) {
 		}
 
 		cval.function = (*C.struct_LuaFunction)(unsafe.Pointer(ptr))
-	}, func() {
-		fmt.Println("loader is being dropped")
-	})
+	}, nil)
 
 	res := C.luago_create_require_function(lua, C.struct_GoRequire{
 		is_require_allowed: isRequireAllowed.ToC(),
@@ -297,5 +336,5 @@ func (l *Lua) CreateRequireFunction(require Require) (*LuaFunction, error) {
 		return nil, err
 	}
 
-	return &LuaFunction{object: newObject((*C.void)(unsafe.Pointer(res.value)), functionTab), lua: l}, nil
+	return &LuaFunction{object: l.newObject((*C.void)(unsafe.Pointer(res.value)), functionTab), lua: l}, nil
 }
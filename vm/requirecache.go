@@ -0,0 +1,125 @@
+package vm
+
+import (
+	"sort"
+	"strings"
+)
+
+// The registry key Luau stores the results of require in
+const requireCacheKey = "_MODULES"
+
+// RequireCache gives access to the cache of modules loaded by require functions
+// (see CreateRequireFunction), which is keyed by the cache keys of the requirer.
+//
+// Evicted modules are loaded again the next time they are required. Modules that
+// already required an evicted module keep the value they received.
+type RequireCache struct {
+	lua *Lua
+}
+
+// RequireCache returns the require cache of the Lua VM
+func (l *Lua) RequireCache() *RequireCache {
+	return &RequireCache{lua: l}
+}
+
+// Returns the cache table or nil if nothing was required yet
+func (c *RequireCache) table() (*LuaTable, error) {
+	value, err := c.lua.RegistryValue(requireCacheKey)
+	if err != nil {
+		return nil, err
+	}
+	tab, ok := value.(*ValueTable)
+	if !ok {
+		value.Close()
+		return nil, nil
+	}
+	return tab.Value(), nil
+}
+
+// Keys returns the sorted cache keys of all cached modules
+func (c *RequireCache) Keys() ([]string, error) {
+	tab, err := c.table()
+	if err != nil || tab == nil {
+		return nil, err
+	}
+	defer tab.Close()
+
+	var keys []string
+	err = tab.ForEach(func(key, value Value) error {
+		if s, ok := key.(*ValueString); ok {
+			keys = append(keys, s.Value().String())
+		}
+		key.Close()
+		value.Close()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Contains returns whether a module with the given cache key is cached
+func (c *RequireCache) Contains(key string) (bool, error) {
+	tab, err := c.table()
+	if err != nil || tab == nil {
+		return false, err
+	}
+	defer tab.Close()
+
+	value, err := tab.RawGet(GoString(key))
+	if err != nil {
+		return false, err
+	}
+	defer value.Close()
+	return value.Type() != LuaValueNil, nil
+}
+
+// Evict removes the module with the given cache key from the cache, returning whether it was cached
+func (c *RequireCache) Evict(key string) (bool, error) {
+	contained, err := c.Contains(key)
+	if err != nil || !contained {
+		return false, err
+	}
+
+	tab, err := c.table()
+	if err != nil || tab == nil {
+		return false, err
+	}
+	defer tab.Close()
+	if err := tab.RawSet(GoString(key), NewValueNil()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// EvictPrefix removes all modules whose cache key starts with prefix from the cache,
+// returning the evicted keys
+func (c *RequireCache) EvictPrefix(prefix string) ([]string, error) {
+	keys, err := c.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	var evicted []string
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		ok, err := c.Evict(key)
+		if err != nil {
+			return evicted, err
+		}
+		if ok {
+			evicted = append(evicted, key)
+		}
+	}
+	return evicted, nil
+}
+
+// Clear removes all modules from the cache
+func (c *RequireCache) Clear() error {
+	_, err := c.EvictPrefix("")
+	return err
+}
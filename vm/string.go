@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"errors"
 	"unsafe"
 )
 
@@ -10,6 +11,7 @@ import (
 import "C"
 
 var stringTab = objectTab{
+	name: "LuaString",
 	dtor: func(ptr *C.void) {
 		C.luago_free_string((*C.struct_LuaString)(unsafe.Pointer(ptr)))
 	},
@@ -45,6 +47,34 @@ func (l *LuaString) Bytes() []byte {
 	return moveBytesToGo(data)
 }
 
+// WithBytes calls fn with the bytes of the LuaString without copying them
+//
+// The slice is only valid while fn runs and must neither be modified nor retained. The
+// string and its Lua VM are read-locked while fn runs, so neither can be closed until fn
+// returns. The Lua VM cannot be entered from fn: creating values, calling functions,
+// resuming threads and table operations that may invoke metamethods fail with ErrViewOpen,
+// as does closing the Lua VM.
+func (l *LuaString) WithBytes(fn func(b []byte) error) error {
+	if l.lua.object.IsClosed() {
+		return errors.New("cannot use a LuaString of a closed Lua VM")
+	}
+	l.object.RLock()
+	defer l.object.RUnlock()
+	ptr, err := l.innerPtr()
+	if err != nil {
+		return err
+	}
+
+	data := C.luago_string_as_bytes(ptr)
+	var b []byte
+	if data.data != nil && data.len > 0 {
+		b = unsafe.Slice((*byte)(unsafe.Pointer(data.data)), int(data.len))
+	}
+	return l.lua.withView(func() error {
+		return fn(b)
+	})
+}
+
 // Returns the LuaString as a byte slice with nul terminator
 func (l *LuaString) BytesWithNUL() []byte {
 	if l.lua.object.IsClosed() {
@@ -11,6 +11,7 @@ import (
 )
 
 var tableTab = objectTab{
+	name: "LuaTable",
 	dtor: func(ptr *C.void) {
 		C.luago_free_table((*C.struct_LuaTable)(unsafe.Pointer(ptr)))
 	},
@@ -85,6 +86,9 @@ func (l *LuaTable) Equals(other *LuaTable) (bool, error) {
 	if l.lua.object.IsClosed() {
 		return false, fmt.Errorf("cannot compare LuaTable on closed Lua VM")
 	}
+	if err := l.lua.checkNoView(); err != nil {
+		return false, err
+	}
 
 	if other == nil {
 		return false, nil
@@ -153,9 +157,7 @@ func (l *LuaTable) ForEach(fn TableForEachFn) error {
 			errv = err               // Capture the error to return it later
 			cval.stop = C.bool(true) // Stop the iteration
 		}
-	}, func() {
-		fmt.Println("foreach callback is being dropped")
-	})
+	}, nil)
 
 	res := C.luago_table_foreach(ptr, cbWrapper.ToC())
 	if res.error != nil {
@@ -205,9 +207,7 @@ func (l *LuaTable) ForEachValue(fn TableForEachValueFn) error {
 			errv = err               // Capture the error to return it later
 			cval.stop = C.bool(true) // Stop the iteration
 		}
-	}, func() {
-		fmt.Println("foreachvalue callback is being dropped")
-	})
+	}, nil)
 
 	res := C.luago_table_foreach_value(ptr, cbWrapper.ToC())
 	if res.error != nil {
@@ -227,6 +227,9 @@ func (l *LuaTable) Get(key Value) (Value, error) {
 	if l.lua.object.IsClosed() {
 		return &ValueNil{}, fmt.Errorf("cannot get key from table on closed Lua VM")
 	}
+	if err := l.lua.checkNoView(); err != nil {
+		return &ValueNil{}, err
+	}
 
 	l.object.RLock()
 	defer l.object.RUnlock()
@@ -298,6 +301,9 @@ func (l *LuaTable) Len() (int64, error) {
 	if l.lua.object.IsClosed() {
 		return 0, fmt.Errorf("cannot get length of table on closed Lua VM")
 	}
+	if err := l.lua.checkNoView(); err != nil {
+		return 0, err
+	}
 
 	l.object.RLock()
 	defer l.object.RUnlock()
@@ -336,7 +342,7 @@ func (l *LuaTable) Metatable() *LuaTable {
 		return nil // No metatable or the table is closed
 	}
 
-	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(res)), tableTab), lua: l.lua}
+	return &LuaTable{object: l.lua.newObject((*C.void)(unsafe.Pointer(res)), tableTab), lua: l.lua}
 }
 
 // Pop removes the last element from the LuaTable
@@ -346,6 +352,9 @@ func (l *LuaTable) Pop() (Value, error) {
 	if l.lua.object.IsClosed() {
 		return &ValueNil{}, fmt.Errorf("cannot pop from table on closed Lua VM")
 	}
+	if err := l.lua.checkNoView(); err != nil {
+		return &ValueNil{}, err
+	}
 
 	l.object.RLock()
 	defer l.object.RUnlock()
@@ -368,6 +377,9 @@ func (l *LuaTable) Push(value Value) error {
 	if l.lua.object.IsClosed() {
 		return fmt.Errorf("cannot push to table on closed Lua VM")
 	}
+	if err := l.lua.checkNoView(); err != nil {
+		return err
+	}
 
 	l.object.RLock()
 	defer l.object.RUnlock()
@@ -573,6 +585,9 @@ func (l *LuaTable) Set(key Value, value Value) error {
 	if l.lua.object.IsClosed() {
 		return fmt.Errorf("cannot set key in table on closed Lua VM")
 	}
+	if err := l.lua.checkNoView(); err != nil {
+		return err
+	}
 
 	l.object.RLock()
 	defer l.object.RUnlock()
@@ -10,6 +10,7 @@ import (
 )
 
 var threadTab = objectTab{
+	name: "LuaThread",
 	dtor: func(ptr *C.void) {
 		C.luago_free_thread((*C.struct_LuaThread)(unsafe.Pointer(ptr)))
 	},
@@ -163,7 +164,9 @@ func (l *LuaThread) Resume(args ...Value) ([]Value, error) {
 	if l.lua.object.IsClosed() {
 		return nil, fmt.Errorf("cannot resume thread on closed Lua VM")
 	}
-
+	if err := l.lua.checkNoView(); err != nil {
+		return nil, err
+	}
 	l.object.RLock()
 	defer l.object.RUnlock()
 
@@ -176,9 +179,10 @@ func (l *LuaThread) Resume(args ...Value) ([]Value, error) {
 		return nil, err // Return error if the value cannot be converted (diff lua state, closed object, etc)
 	}
 
+	l.lua.clearPendingError()
 	res := C.luago_thread_resume(ptr, mw.ptr)
 	if res.error != nil {
-		return nil, moveErrorToGo(res.error)
+		return nil, l.lua.wrapCallError(moveErrorToGo(res.error))
 	}
 	rets := &luaMultiValue{ptr: res.value, lua: l.lua}
 	retsMw := rets.take()
@@ -195,7 +199,9 @@ func (l *LuaThread) ResumeError(errorValue Value) ([]Value, error) {
 	if l.lua.object.IsClosed() {
 		return nil, fmt.Errorf("cannot resume thread on closed Lua VM")
 	}
-
+	if err := l.lua.checkNoView(); err != nil {
+		return nil, err
+	}
 	l.object.RLock()
 	defer l.object.RUnlock()
 
@@ -208,9 +214,10 @@ func (l *LuaThread) ResumeError(errorValue Value) ([]Value, error) {
 		return nil, err // Return error if the value cannot be converted (diff lua state, closed object, etc)
 	}
 
+	l.lua.clearPendingError()
 	res := C.luago_thread_resume_error(ptr, errorValueC)
 	if res.error != nil {
-		return nil, moveErrorToGo(res.error)
+		return nil, l.lua.wrapCallError(moveErrorToGo(res.error))
 	}
 	rets := &luaMultiValue{ptr: res.value, lua: l.lua}
 	retsMw := rets.take()
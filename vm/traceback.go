@@ -0,0 +1,90 @@
+package vm
+
+/*
+#include "../rustlib/rustlib.h"
+*/
+import "C"
+import (
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// A StackFrame is a single frame of a traceback captured when an error occurred
+type StackFrame struct {
+	// The name of the function, if known
+	Name string
+	// What kind of function this frame belongs to ("Lua", "C" or "main")
+	//
+	// Frames of Go functions have a What of "C"
+	What string
+	// The (short) source of the function
+	Source string
+	// The line currently being executed, or -1 if not known (e.g. for Go functions)
+	Line int
+}
+
+// String returns the frame formatted similar to a debug.traceback line
+func (f StackFrame) String() string {
+	var sb strings.Builder
+	sb.WriteString(f.Source)
+	if f.Line >= 0 {
+		sb.WriteString(":")
+		sb.WriteString(strconv.Itoa(f.Line))
+	}
+	if f.Name != "" {
+		sb.WriteString(" function ")
+		sb.WriteString(f.Name)
+	}
+	return sb.String()
+}
+
+// A TracebackError is returned by calls made with traceback capture
+// enabled (see LuaFunction.CallWithTraceback and Lua.SetTracebackOnError)
+//
+// Frames are ordered from the innermost frame (where the error was raised) outwards
+type TracebackError struct {
+	Message string
+	Frames  []StackFrame
+
+	cause error // Typed error raised by gluau that caused the error (if any)
+}
+
+// Traceback returns the captured frames formatted like debug.traceback
+func (e *TracebackError) Traceback() string {
+	var sb strings.Builder
+	sb.WriteString("stack traceback:")
+	for _, frame := range e.Frames {
+		sb.WriteString("\n\t")
+		sb.WriteString(frame.String())
+	}
+	return sb.String()
+}
+
+func (e *TracebackError) Error() string {
+	return e.Message + "\n" + e.Traceback()
+}
+
+func (e *TracebackError) Unwrap() error {
+	return e.cause
+}
+
+// Takes ownership of the frames returned by the Rust side
+func moveStackFramesToGo(frames *C.struct_GoStackFrame, nframes C.size_t) []StackFrame {
+	if frames == nil || nframes == 0 {
+		return nil
+	}
+
+	cFrames := unsafe.Slice(frames, int(nframes))
+	goFrames := make([]StackFrame, len(cFrames))
+	for i, frame := range cFrames {
+		goFrames[i] = StackFrame{
+			Name:   moveStringToGo(frame.name),
+			What:   moveStringToGo(frame.what),
+			Source: moveStringToGo(frame.source),
+			Line:   int(frame.line),
+		}
+	}
+	C.luago_free_stack_frames(frames, nframes)
+	return goFrames
+}
@@ -11,6 +11,7 @@ import (
 )
 
 var userdataTab = objectTab{
+	name: "LuaUserData",
 	dtor: func(ptr *C.void) {
 		C.luago_free_userdata((*C.struct_LuaUserData)(unsafe.Pointer(ptr)))
 	},
@@ -130,7 +131,7 @@ func (l *LuaUserData) Metatable() (*LuaTable, error) {
 		return nil, err
 	}
 
-	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(res.value)), tableTab), lua: l.lua}, nil
+	return &LuaTable{object: l.lua.newObject((*C.void)(unsafe.Pointer(res.value)), tableTab), lua: l.lua}, nil
 }
 
 // ToValue converts the LuaUserData to a Value.
@@ -710,32 +710,32 @@ func (l *Lua) valueFromC(item C.struct_GoLuaValue) Value {
 	case C.LuaValueTypeString:
 		ptrToPtr := (**C.struct_LuaString)(unsafe.Pointer(&item.data))
 		strPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
-		str := &LuaString{object: newObject(strPtr, stringTab), lua: l}
+		str := &LuaString{object: l.newObject(strPtr, stringTab), lua: l}
 		return &ValueString{value: str}
 	case C.LuaValueTypeTable:
 		ptrToPtr := (**C.struct_LuaTable)(unsafe.Pointer(&item.data))
 		tabPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
-		tab := &LuaTable{object: newObject(tabPtr, tableTab), lua: l}
+		tab := &LuaTable{object: l.newObject(tabPtr, tableTab), lua: l}
 		return &ValueTable{value: tab}
 	case C.LuaValueTypeFunction:
 		ptrToPtr := (**C.struct_LuaFunction)(unsafe.Pointer(&item.data))
 		funcPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
-		funct := &LuaFunction{object: newObject(funcPtr, functionTab), lua: l}
+		funct := &LuaFunction{object: l.newObject(funcPtr, functionTab), lua: l}
 		return &ValueFunction{value: funct}
 	case C.LuaValueTypeThread:
 		ptrToPtr := (**C.struct_LuaThread)(unsafe.Pointer(&item.data))
 		threadPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
-		thread := &LuaThread{object: newObject(threadPtr, threadTab), lua: l}
+		thread := &LuaThread{object: l.newObject(threadPtr, threadTab), lua: l}
 		return &ValueThread{value: thread}
 	case C.LuaValueTypeUserData:
 		ptrToPtr := (**C.struct_LuaUserData)(unsafe.Pointer(&item.data))
 		udPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
-		udt := &LuaUserData{object: newObject(udPtr, userdataTab), lua: l}
+		udt := &LuaUserData{object: l.newObject(udPtr, userdataTab), lua: l}
 		return &ValueUserData{value: udt}
 	case C.LuaValueTypeBuffer:
 		ptrToPtr := (**C.struct_LuaBuffer)(unsafe.Pointer(&item.data))
 		bufPtr := (*C.void)(unsafe.Pointer(*ptrToPtr))
-		buf := &LuaBuffer{object: newObject(bufPtr, bufferTab), lua: l}
+		buf := &LuaBuffer{object: l.newObject(bufPtr, bufferTab), lua: l}
 		return &ValueBuffer{value: buf}
 	case C.LuaValueTypeOther:
 		// Currently, always nil
@@ -7,18 +7,50 @@ import "C"
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
 var luaVmTab = objectTab{
+	name: "Lua",
 	dtor: func(ptr *C.void) {
 		C.freeluavm((*C.struct_Lua)(unsafe.Pointer(ptr)))
 	},
 }
 
+// The object table of Lua VMs created by CreateLuaVmComplex (as opposed to handles passed to callbacks)
+var mainLuaVmTab = objectTab{
+	name: "Lua",
+	dtor: func(ptr *C.void) {
+		C.luago_close_luavm((*C.struct_Lua)(unsafe.Pointer(ptr)))
+	},
+}
+
 // A handle to the Lua VM.
 type Lua struct {
 	object *object
+
+	tracebackOnError atomic.Bool   // Whether calls should capture a traceback on error
+	manualGCs        atomic.Uint64 // Number of GC cycles completed through GCCollect/GCStep
+
+	interruptMu sync.Mutex
+	interrupt   InterruptFn // The interrupt set by SetInterrupt
+
+	memCategories   memoryCategories
+	pendingLimitErr atomic.Pointer[MemoryCategoryLimitError] // Set when a memory category limit interrupts execution
+
+	handles atomic.Pointer[handleTracker] // Set if handle tracking is enabled
+
+	closeMu sync.Mutex
+	onClose []func() // Functions to call when the VM is closed
+
+	callback bool // Whether this is a handle to the Lua VM passed to a callback (see callbackLua)
+
+	views   atomic.Int32 // Number of open zero-copy views (see LuaString.WithBytes)
+	closing atomic.Bool  // Set once Close has started, after which no view can be opened
 }
 
 // Returns the string representation of the Lua VM.
@@ -33,7 +65,54 @@ func (l *Lua) String() string {
 	return fmt.Sprintf("Lua VM: 0x%x", pt)
 }
 
+// ErrViewOpen is returned when the Lua VM is used or closed while a zero-copy view of one
+// of its strings or buffers is open (see LuaString.WithBytes and LuaBuffer.WithBytes)
+var ErrViewOpen = errors.New("cannot use the Lua VM while a zero-copy view is open")
+
+// Returns ErrViewOpen if a zero-copy view is open
+func (l *Lua) checkNoView() error {
+	if l.views.Load() > 0 {
+		return ErrViewOpen
+	}
+	return nil
+}
+
+// Calls fn with a zero-copy view of a string or buffer of the Lua VM open
+//
+// Running Luau code could free the viewed value, so the Lua VM cannot be entered while fn
+// runs: creating values, calling functions, resuming threads and table operations that may
+// invoke metamethods fail with ErrViewOpen. The view also read-locks the Lua VM, so it
+// cannot be closed until fn returns.
+func (l *Lua) withView(fn func() error) error {
+	l.object.RLock()
+	defer l.object.RUnlock()
+	if l.closing.Load() {
+		return errors.New("cannot open a view of a closing Lua VM")
+	}
+	l.views.Add(1)
+	defer l.views.Add(-1)
+	return fn()
+}
+
+// Returns a new handle to the Lua VM which keeps it alive until freed using C.freeluavm
+//
+// The Lua VM is only locked while the handle is created, so the handle can be used for
+// calls that may close objects of the Lua VM (or the Lua VM itself) while they run.
+func (l *Lua) cloneHandle() (*C.struct_Lua, error) {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return nil, err
+	}
+	return C.luago_clone_luavm(lua), nil
+}
+
 func (l *Lua) lua() (*C.struct_Lua, error) {
+	if err := l.checkNoView(); err != nil {
+		return nil, err
+	}
 	ptr, err := l.object.PointerNoLock()
 	if err != nil {
 		return nil, err // Return error if the object is closed
@@ -106,6 +185,14 @@ func (l *Lua) MemoryLimit() int {
 	return int(limit)
 }
 
+// SetTracebackOnError sets whether LuaFunction.Call should capture a traceback
+// at the point of an error, returning a *TracebackError on failure.
+//
+// See LuaFunction.CallWithTraceback for more information
+func (l *Lua) SetTracebackOnError(enabled bool) {
+	l.tracebackOnError.Store(enabled)
+}
+
 type TypeMetatableType uint8
 
 const (
@@ -257,7 +344,7 @@ func (l *Lua) Globals() *LuaTable {
 	if globals == nil {
 		return nil // Return nil if the globals table is not available
 	}
-	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(globals)), tableTab), lua: l}
+	return &LuaTable{object: l.newObject((*C.void)(unsafe.Pointer(globals)), tableTab), lua: l}
 }
 
 // SetGlobals sets the global environment table of the Lua VM.
@@ -311,12 +398,40 @@ type InterruptFn func(funcVm *CallbackLua) (VmState, error)
 //
 // Also this can be used to implement continuous execution limits by instructing Luau VM to yield by returning VmState::Yield.
 func (l *Lua) SetInterrupt(callback InterruptFn) {
+	l.interruptMu.Lock()
+	defer l.interruptMu.Unlock()
+
+	l.interrupt = callback
+	l.updateInterrupt()
+}
+
+// Removes the interrupt function set by SetInterrupt.
+func (l *Lua) RemoveInterrupt() {
+	l.interruptMu.Lock()
+	defer l.interruptMu.Unlock()
+
+	l.interrupt = nil
+	l.updateInterrupt()
+}
+
+// Installs (or removes) the interrupt of the Lua VM based on the interrupt
+// set by SetInterrupt and any memory category limits
+//
+// The caller must hold interruptMu
+func (l *Lua) updateInterrupt() error {
 	l.object.RLock()
 	defer l.object.RUnlock()
 
 	lua, err := l.lua()
 	if err != nil {
-		return
+		return err
+	}
+
+	callback := l.interrupt
+	checkLimits := l.memCategories.hasLimits()
+	if callback == nil && !checkLimits {
+		C.luago_remove_interrupt(lua)
+		return nil
 	}
 
 	cbWrapper := newGoCallback(func(val unsafe.Pointer) {
@@ -338,7 +453,23 @@ func (l *Lua) SetInterrupt(callback InterruptFn) {
 			}
 		}()
 
-		callbackVm := &Lua{object: newObject((*C.void)(unsafe.Pointer(cval.lua)), luaVmTab)}
+		if checkLimits {
+			if err := l.CheckMemoryCategoryLimits(); err != nil {
+				if limitErr, ok := err.(*MemoryCategoryLimitError); ok {
+					l.pendingLimitErr.Store(limitErr)
+				}
+				errv := moveStringToRust(err.Error())
+				cval.error = errv // Rust side will deallocate it for us
+				return
+			}
+		}
+
+		if callback == nil {
+			cval.vm_state = C.uint8_t(VmStateContinue)
+			return
+		}
+
+		callbackVm := l.callbackLua(cval.lua)
 		defer callbackVm.Close() // Free the memory associated with the callback VM. TODO: Maybe switch to using a Deref API instead of Close?
 
 		cbLua := &CallbackLua{
@@ -355,24 +486,10 @@ func (l *Lua) SetInterrupt(callback InterruptFn) {
 		}
 
 		cval.vm_state = C.uint8_t(vmState)
-	}, func() {
-		fmt.Println("interrupt callback is being dropped")
-	})
+	}, nil)
 
 	C.luago_set_interrupt(lua, cbWrapper.ToC())
-}
-
-// Removes the interrupt function set by SetInterrupt.
-func (l *Lua) RemoveInterrupt() {
-	l.object.RLock()
-	defer l.object.RUnlock()
-
-	lua, err := l.lua()
-	if err != nil {
-		return // No-op if the Lua VM is closed
-	}
-
-	C.luago_remove_interrupt(lua)
+	return nil
 }
 
 // Returns the main thread of the Lua VM.
@@ -392,7 +509,7 @@ func (l *Lua) MainThread() *LuaThread {
 		return nil // Return nil if the main thread is not available
 	}
 
-	return &LuaThread{object: newObject((*C.void)(unsafe.Pointer(thread)), threadTab), lua: l}
+	return &LuaThread{object: l.newObject((*C.void)(unsafe.Pointer(thread)), threadTab), lua: l}
 }
 
 // CreateString creates a Lua string from a Go string.
@@ -421,14 +538,14 @@ func (l *Lua) createString(s []byte) (*LuaString, error) {
 		if res.error != nil {
 			return nil, moveErrorToGo(res.error)
 		}
-		return &LuaString{object: newObject((*C.void)(unsafe.Pointer(res.value)), stringTab), lua: l}, nil
+		return &LuaString{object: l.newObject((*C.void)(unsafe.Pointer(res.value)), stringTab), lua: l}, nil
 	}
 
 	res := C.luago_create_string(lua, (*C.char)(unsafe.Pointer(&s[0])), C.size_t(len(s)))
 	if res.error != nil {
 		return nil, moveErrorToGo(res.error)
 	}
-	return &LuaString{object: newObject((*C.void)(unsafe.Pointer(res.value)), stringTab), lua: l}, nil
+	return &LuaString{object: l.newObject((*C.void)(unsafe.Pointer(res.value)), stringTab), lua: l}, nil
 }
 
 // Create string as pointer (without any finalizer)
@@ -472,7 +589,7 @@ func (l *Lua) CreateTable() (*LuaTable, error) {
 		err := moveErrorToGo(res.error)
 		return nil, err
 	}
-	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(res.value)), tableTab), lua: l}, nil
+	return &LuaTable{object: l.newObject((*C.void)(unsafe.Pointer(res.value)), tableTab), lua: l}, nil
 }
 
 // CreateTableWithCapacity creates a new Lua table with specified capacity for array and record parts.
@@ -491,7 +608,7 @@ func (l *Lua) CreateTableWithCapacity(narr, nrec int) (*LuaTable, error) {
 		err := moveErrorToGo(res.error)
 		return nil, err
 	}
-	return &LuaTable{object: newObject((*C.void)(unsafe.Pointer(res.value)), tableTab), lua: l}, nil
+	return &LuaTable{object: l.newObject((*C.void)(unsafe.Pointer(res.value)), tableTab), lua: l}, nil
 }
 
 type FunctionFn func(funcVm *CallbackLua, args []Value) ([]Value, error)
@@ -536,7 +653,7 @@ func (l *Lua) CreateFunction(callback FunctionFn) (*LuaFunction, error) {
 		mw := &luaMultiValue{ptr: cval.args, lua: l}
 		args := mw.take()
 
-		callbackVm := &Lua{object: newObject((*C.void)(unsafe.Pointer(cval.lua)), luaVmTab)}
+		callbackVm := l.callbackLua(cval.lua)
 		defer callbackVm.Close() // Free the memory associated with the callback VM. TODO: Maybe switch to using a Deref API instead of Close?
 
 		cbLua := &CallbackLua{
@@ -560,9 +677,7 @@ func (l *Lua) CreateFunction(callback FunctionFn) (*LuaFunction, error) {
 		}
 
 		cval.values = outMw.ptr // Rust will deallocate values as well
-	}, func() {
-		fmt.Println("function callback is being dropped")
-	})
+	}, nil)
 
 	res := C.luago_create_function(lua, cbWrapper.ToC())
 	if res.error != nil {
@@ -570,7 +685,7 @@ func (l *Lua) CreateFunction(callback FunctionFn) (*LuaFunction, error) {
 		return nil, err
 	}
 
-	return &LuaFunction{object: newObject((*C.void)(unsafe.Pointer(res.value)), functionTab), lua: l}, nil
+	return &LuaFunction{object: l.newObject((*C.void)(unsafe.Pointer(res.value)), functionTab), lua: l}, nil
 }
 
 // CreateThread creates a new thread from a LuaFunction
@@ -606,7 +721,7 @@ func (l *Lua) CreateThread(fn *LuaFunction) (*LuaThread, error) {
 		err := moveErrorToGo(res.error)
 		return nil, err
 	}
-	return &LuaThread{object: newObject((*C.void)(unsafe.Pointer(res.value)), threadTab), lua: l}, nil
+	return &LuaThread{object: l.newObject((*C.void)(unsafe.Pointer(res.value)), threadTab), lua: l}, nil
 }
 
 // CreateBuffer creates a LuaBuffer from a byte slice.
@@ -625,14 +740,33 @@ func (l *Lua) CreateBuffer(s []byte) (*LuaBuffer, error) {
 		if res.error != nil {
 			return nil, moveErrorToGo(res.error)
 		}
-		return &LuaBuffer{object: newObject((*C.void)(unsafe.Pointer(res.value)), bufferTab), lua: l}, nil
+		return &LuaBuffer{object: l.newObject((*C.void)(unsafe.Pointer(res.value)), bufferTab), lua: l}, nil
 	}
 
 	res := C.luago_create_buffer(lua, (*C.char)(unsafe.Pointer(&s[0])), C.size_t(len(s)))
 	if res.error != nil {
 		return nil, moveErrorToGo(res.error)
 	}
-	return &LuaBuffer{object: newObject((*C.void)(unsafe.Pointer(res.value)), bufferTab), lua: l}, nil
+	return &LuaBuffer{object: l.newObject((*C.void)(unsafe.Pointer(res.value)), bufferTab), lua: l}, nil
+}
+
+// CreateBufferSize creates a zero-filled LuaBuffer of the given size.
+//
+// Unlike CreateBuffer, no Go slice needs to be allocated and copied into the buffer.
+func (l *Lua) CreateBufferSize(size uint64) (*LuaBuffer, error) {
+	l.object.RLock()
+	defer l.object.RUnlock()
+
+	lua, err := l.lua()
+	if err != nil {
+		return nil, err
+	}
+
+	res := C.luago_create_buffer_with_capacity(lua, C.size_t(size))
+	if res.error != nil {
+		return nil, moveErrorToGo(res.error)
+	}
+	return &LuaBuffer{object: l.newObject((*C.void)(unsafe.Pointer(res.value)), bufferTab), lua: l}, nil
 }
 
 // LoadChunk loads a Lua chunk from the given options.
@@ -683,11 +817,54 @@ func (l *Lua) LoadChunk(opts ChunkOpts) (*LuaFunction, error) {
 		err := moveErrorToGo(res.error)
 		return nil, err
 	}
-	return &LuaFunction{object: newObject((*C.void)(unsafe.Pointer(res.value)), functionTab), lua: l}, nil
+	return &LuaFunction{object: l.newObject((*C.void)(unsafe.Pointer(res.value)), functionTab), lua: l}, nil
+}
+
+// UserDataOpts controls what happens when user data created using CreateUserDataOpts is collected.
+type UserDataOpts struct {
+	// Called once when Luau collects the user data (or the Lua VM is closed)
+	//
+	// This may be called from within the garbage collector, so the Lua VM must not be used
+	// from within OnCollect.
+	OnCollect func(associatedData any)
+
+	// If set, the associated data is closed when the user data is collected if it implements io.Closer
+	//
+	// Close is called after OnCollect and its error is ignored.
+	CloseOnCollect bool
+}
+
+// Returns the drop function for the associated data of user data
+func (opts UserDataOpts) dropFunc(associatedData any) func() {
+	closer, isCloser := associatedData.(io.Closer)
+	if opts.OnCollect == nil && !(opts.CloseOnCollect && isCloser) {
+		return nil
+	}
+
+	return func() {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Fprintln(os.Stderr, "panic while collecting userdata:", r)
+			}
+		}()
+
+		if opts.OnCollect != nil {
+			opts.OnCollect(associatedData)
+		}
+		if opts.CloseOnCollect && isCloser {
+			_ = closer.Close()
+		}
+	}
 }
 
 // CreateUserData creates a LuaUserData with associated data and a metatable.
 func (l *Lua) CreateUserData(associatedData any, mt *LuaTable) (*LuaUserData, error) {
+	return l.CreateUserDataOpts(associatedData, mt, UserDataOpts{})
+}
+
+// CreateUserDataOpts creates a LuaUserData with associated data and a metatable,
+// with hooks that run when the user data is collected.
+func (l *Lua) CreateUserDataOpts(associatedData any, mt *LuaTable, opts UserDataOpts) (*LuaUserData, error) {
 	if mt == nil {
 		return nil, fmt.Errorf("metatable cannot be nil")
 	}
@@ -710,9 +887,7 @@ func (l *Lua) CreateUserData(associatedData any, mt *LuaTable) (*LuaUserData, er
 		return nil, err // Return error if the metatable is closed
 	}
 
-	dynData := newDynamicData(associatedData, func() {
-		fmt.Println("dynamic data is being dropped")
-	})
+	dynData := newDynamicData(associatedData, opts.dropFunc(associatedData))
 	cDynData := dynData.ToC()
 	res := C.luago_create_userdata(lua, cDynData, mtPtr)
 	if res.error != nil {
@@ -721,7 +896,7 @@ func (l *Lua) CreateUserData(associatedData any, mt *LuaTable) (*LuaUserData, er
 	}
 	return &LuaUserData{
 		lua:    l,
-		object: newObject((*C.void)(unsafe.Pointer(res.value)), userdataTab),
+		object: l.newObject((*C.void)(unsafe.Pointer(res.value)), userdataTab),
 	}, nil
 }
 
@@ -729,11 +904,49 @@ func (l *Lua) Close() error {
 	if l == nil || l.object == nil {
 		return nil // Nothing to close
 	}
+	// Views hold a read lock of the VM, so checking them under the write lock (which Close of
+	// the object takes as well) makes sure none is open or can be opened from here on
+	if !l.object.TryLock() {
+		if l.views.Load() > 0 {
+			return ErrViewOpen
+		}
+		return errors.New("recursive lock detected, cannot close object")
+	}
+	l.closing.Store(true)
+	l.object.Unlock()
+
+	l.closeMu.Lock()
+	onClose := l.onClose
+	l.onClose = nil
+	l.closeMu.Unlock()
+	for _, fn := range onClose {
+		fn()
+	}
+
+	if !l.callback && !l.object.IsClosed() {
+		l.reportLeakedHandles()
+	}
 
 	// Close the Lua VM object
 	return l.object.Close()
 }
 
+// OnClose registers a function to be called when the Lua VM is closed
+//
+// The functions are called in registration order before the VM itself is closed, so
+// they can still release handles belonging to the VM. If the VM is already closed,
+// fn is called immediately.
+func (l *Lua) OnClose(fn func()) {
+	l.closeMu.Lock()
+	if l.object.IsClosed() {
+		l.closeMu.Unlock()
+		fn()
+		return
+	}
+	l.onClose = append(l.onClose, fn)
+	l.closeMu.Unlock()
+}
+
 type StdLib uint32
 
 const (
@@ -750,6 +963,16 @@ const (
 	StdLibAll       StdLib = 1 << 31 // All standard libraries
 )
 
+// Wraps the handle to the Lua VM passed to a callback of l
+//
+// Handles created through it are tracked by the handle tracker of l (if enabled). Closing it
+// only frees the handle, so leaked handles are reported when l is closed.
+func (l *Lua) callbackLua(ptr *C.struct_Lua) *Lua {
+	cb := &Lua{object: newObject((*C.void)(unsafe.Pointer(ptr)), luaVmTab), callback: true}
+	cb.handles.Store(l.handles.Load())
+	return cb
+}
+
 // CreateLuaVm creates a new Lua VM with the entire standard library enabled.
 func CreateLuaVm() (*Lua, error) {
 	return CreateLuaVmComplex(StdLibAll)
@@ -764,7 +987,10 @@ func CreateLuaVmComplex(stdlib StdLib) (*Lua, error) {
 	if ptr == nil {
 		return nil, fmt.Errorf("failed to create Lua VM")
 	}
-	vm := &Lua{object: newObject((*C.void)(unsafe.Pointer(ptr)), luaVmTab)}
+	vm := &Lua{object: newObject((*C.void)(unsafe.Pointer(ptr)), mainLuaVmTab)}
+	if trackHandlesByDefault {
+		vm.EnableHandleTracking(nil)
+	}
 	return vm, nil
 }
 
@@ -812,7 +1038,7 @@ func (c *CallbackLua) CurrentThread() *LuaThread {
 		return nil // Return nil if the callback thread is not available
 	}
 
-	return &LuaThread{object: newObject((*C.void)(unsafe.Pointer(thread)), threadTab), lua: c.mainstate}
+	return &LuaThread{object: c.mainstate.newObject((*C.void)(unsafe.Pointer(thread)), threadTab), lua: c.mainstate}
 }
 
 // Sets the arguments to yield the thread with.
@@ -0,0 +1,304 @@
+package vmutils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/koeng101/gluau/vm"
+)
+
+// A boundType is a type whose values can be converted to userdata
+type boundType interface {
+	typeName() string
+	createAny(lua *vm.Lua, data any) (*vm.LuaUserData, error)
+}
+
+// Registry of types bound using BindType keyed by the pointer type (*T)
+var boundTypes sync.Map
+
+func lookupBoundType(t reflect.Type) boundType {
+	b, ok := boundTypes.Load(t)
+	if !ok {
+		return nil
+	}
+	return b.(boundType)
+}
+
+// BindOptions controls how BindType exposes a Go type to Luau
+type BindOptions struct {
+	// The type name of the userdata (as returned by typeof). Defaults to the name of the Go type
+	TypeName string
+	// Optionally renames fields and methods that do not have a name set using a `luau` tag
+	// (e.g. to convert them to camelCase). Returning "" excludes the field or method.
+	NameFunc func(goName string) string
+	// Makes all fields read-only
+	ReadOnlyFields bool
+}
+
+// A BoundType is a Go type exposed to Luau as userdata using reflection
+//
+// Create a BoundType using BindType.
+type BoundType[T any] struct {
+	tud *TypedUserData[T]
+}
+
+// BindType creates a userdata binding for T using reflection
+//
+// All exported fields of T (if T is a struct) are exposed as properties and all exported methods
+// of *T are exposed as methods. Arguments and return values are converted automatically (see
+// ToValue and FromValue), a trailing error return value is raised as a Luau error and a leading
+// *vm.CallbackLua parameter is passed the calling Lua state.
+//
+// Fields can be renamed using a `luau:"name"` tag, excluded using `luau:"-"` and made read-only
+// using `luau:"name,readonly"` (or `luau:",readonly"` to keep the name).
+//
+// Once bound, *T (and T) values are converted to userdata whenever they are passed to Luau
+// through ToValue (including as arguments/return values of other bound types).
+func BindType[T any](opts BindOptions) (*BoundType[T], error) {
+	ptrType := reflect.TypeOf((*T)(nil))
+	typ := ptrType.Elem()
+
+	typeName := opts.TypeName
+	if typeName == "" {
+		typeName = typ.Name()
+		if typeName == "" {
+			return nil, errors.New("a TypeName must be set when binding unnamed types")
+		}
+	}
+	nameFunc := opts.NameFunc
+	if nameFunc == nil {
+		nameFunc = func(goName string) string { return goName }
+	}
+
+	tud := NewTypedUserData[T]()
+	tud.SetTypeName(typeName)
+
+	if typ.Kind() == reflect.Struct {
+		for _, field := range structFields(typ, nameFunc) {
+			field := field
+			tud.fieldGetters[field.name] = func(self *T, funcVm *vm.CallbackLua) (vm.Value, error) {
+				fv, err := reflect.ValueOf(self).Elem().FieldByIndexErr(field.index)
+				if err != nil {
+					return vm.NewValueNil(), nil // Nil embedded pointer
+				}
+				// Return a reference to struct fields of bound types so they can be modified in place
+				if fv.Kind() == reflect.Struct && lookupBoundType(reflect.PointerTo(fv.Type())) != nil {
+					fv = fv.Addr()
+				}
+				return toValue(funcVm.MainState(), fv)
+			}
+
+			if opts.ReadOnlyFields || field.readOnly {
+				continue
+			}
+			tud.fieldSetters[field.name] = func(self *T, funcVm *vm.CallbackLua, value vm.Value) error {
+				converted, err := fromValue(value, field.typ)
+				if err != nil {
+					return fmt.Errorf("invalid value for field '%s' (%w)", field.name, err)
+				}
+				closeUnlessRetained(value, converted)
+				fv, err := reflect.ValueOf(self).Elem().FieldByIndexErr(field.index)
+				if err != nil {
+					return err
+				}
+				fv.Set(converted)
+				return nil
+			}
+		}
+	}
+
+	for i := 0; i < ptrType.NumMethod(); i++ {
+		method := ptrType.Method(i)
+		name := nameFunc(method.Name)
+		if name == "" {
+			continue
+		}
+		fn := method.Func
+		tud.methods[name] = func(self *T, funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+			return callReflect(funcVm, fn, name, reflect.ValueOf(self), args)
+		}
+	}
+
+	b := &BoundType[T]{tud: tud}
+	boundTypes.Store(ptrType, b)
+	return b, nil
+}
+
+// TypedUserData returns the underlying TypedUserData, which can be used to add more
+// fields, methods and metamethods to the binding before it is first used
+func (b *BoundType[T]) TypedUserData() *TypedUserData[T] {
+	return b.tud
+}
+
+// Create creates a new userdata wrapping data
+func (b *BoundType[T]) Create(lua *vm.Lua, data *T) (*vm.LuaUserData, error) {
+	return b.tud.Create(lua, data)
+}
+
+func (b *BoundType[T]) typeName() string {
+	return b.tud.typename
+}
+
+func (b *BoundType[T]) createAny(lua *vm.Lua, data any) (*vm.LuaUserData, error) {
+	dataT, ok := data.(*T)
+	if !ok {
+		return nil, fmt.Errorf("cannot create userdata of type %s from %T", b.tud.typename, data)
+	}
+	return b.Create(lua, dataT)
+}
+
+// An exposed struct field
+type structField struct {
+	name     string
+	index    []int
+	typ      reflect.Type
+	readOnly bool
+}
+
+// Returns the exported fields of a struct (including promoted fields of embedded structs)
+// taking `luau` tags into account
+func structFields(t reflect.Type, nameFunc func(string) string) []structField {
+	var fields []structField
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		name := field.Name
+		if nameFunc != nil {
+			name = nameFunc(name)
+		}
+		readOnly := false
+		if tag, ok := field.Tag.Lookup("luau"); ok {
+			if tag == "-" {
+				continue
+			}
+			tagName, options, _ := strings.Cut(tag, ",")
+			if tagName != "" {
+				name = tagName
+			}
+			readOnly = options == "readonly"
+		}
+		if name == "" {
+			continue
+		}
+
+		fields = append(fields, structField{
+			name:     name,
+			index:    field.Index,
+			typ:      field.Type,
+			readOnly: readOnly,
+		})
+	}
+	return fields
+}
+
+// Calls fn with args converted to its parameter types, converting the return values back to Luau values
+//
+// If self is valid, it is passed as the first argument of fn (and arguments are numbered
+// starting at 2 in error messages, like Luau does for methods)
+func callReflect(funcVm *vm.CallbackLua, fn reflect.Value, name string, self reflect.Value, args []vm.Value) ([]vm.Value, error) {
+	fnType := fn.Type()
+
+	in := make([]reflect.Value, 0, fnType.NumIn())
+	argOffset := 1 // Luau argument numbers are 1-indexed
+	if self.IsValid() {
+		in = append(in, self)
+		argOffset = 2
+	}
+	if len(in) < fnType.NumIn() && fnType.In(len(in)) == callbackLuaType {
+		in = append(in, reflect.ValueOf(funcVm))
+	}
+
+	paramStart := len(in)
+	numParams := fnType.NumIn() - paramStart
+	variadic := fnType.IsVariadic()
+	if variadic {
+		numParams-- // The variadic parameter is handled separately
+	}
+
+	for i := 0; i < numParams; i++ {
+		converted, err := convertArg(args, i, fnType.In(paramStart+i))
+		if err != nil {
+			closeExtraArgs(args, 0) // fn is not called, so no argument is retained
+			return nil, badArgumentError(i+argOffset, name, err)
+		}
+		in = append(in, converted)
+	}
+
+	if variadic {
+		elemType := fnType.In(fnType.NumIn() - 1).Elem()
+		for i := numParams; i < len(args); i++ {
+			converted, err := convertArg(args, i, elemType)
+			if err != nil {
+				closeExtraArgs(args, 0) // fn is not called, so no argument is retained
+				return nil, badArgumentError(i+argOffset, name, err)
+			}
+			in = append(in, converted)
+		}
+	} else {
+		// Close any extra arguments
+		for i := numParams; i < len(args); i++ {
+			args[i].Close()
+		}
+	}
+
+	out := fn.Call(in)
+
+	// A trailing error is raised as a Luau error
+	if len(out) > 0 && fnType.Out(len(out)-1) == errorType {
+		if errv := out[len(out)-1]; !errv.IsNil() {
+			return nil, errv.Interface().(error)
+		}
+		out = out[:len(out)-1]
+	}
+
+	rets := make([]vm.Value, 0, len(out))
+	for _, o := range out {
+		v, err := toValue(funcVm.MainState(), o)
+		if err != nil {
+			for _, r := range rets {
+				r.Close()
+			}
+			return nil, err
+		}
+		rets = append(rets, v)
+	}
+	return rets, nil
+}
+
+// Converts the argument at index i to a value of type t, closing the argument unless it is retained
+//
+// Missing arguments are only accepted for types that can hold nil (pointers, interfaces, slices
+// and maps), which makes trailing parameters of these types optional.
+func convertArg(args []vm.Value, i int, t reflect.Type) (reflect.Value, error) {
+	if i >= len(args) {
+		if t == valueType {
+			return reflect.ValueOf(vm.NewValueNil()), nil
+		}
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, &ConversionError{Expected: expectedTypeName(t), Got: "no value"}
+	}
+
+	converted, err := fromValue(args[i], t)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	closeUnlessRetained(args[i], converted)
+	return converted, nil
+}
+
+// Returns a Luau-style "bad argument" error
+func badArgumentError(index int, name string, err error) error {
+	if name == "" {
+		name = "?"
+	}
+	return errors.New("bad argument #" + strconv.Itoa(index) + " to '" + name + "' (" + err.Error() + ")")
+}
@@ -0,0 +1,294 @@
+// Package bundle flattens the require graph of a Luau entry module into a single
+// self-contained chunk
+package bundle
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/koeng101/gluau/vm"
+	"github.com/koeng101/gluau/vmutils/require"
+)
+
+// Options for Build
+type Options struct {
+	// The chunk name the bundle is loaded with, used by TranslateError (defaults to "bundle")
+	Name string
+
+	// If set, modules are embedded as strings and loaded using loadstring with their original
+	// chunk names, so errors raised by them report their original file and line
+	//
+	// Otherwise modules are embedded as functions (which works without loadstring and compiles
+	// the whole bundle at once) and errors can be mapped back using Position and TranslateError.
+	PreserveChunkNames bool
+}
+
+// A Warning about a module that could not be bundled fully
+type Warning struct {
+	Chunk   string // The chunk name (path) of the module
+	Line    int
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s:%d: %s", w.Chunk, w.Line, w.Message)
+}
+
+// A range of lines of the bundle holding the code of a module
+type lineRange struct {
+	chunk string
+	start int // The line of the bundle holding the first line of the module
+	lines int
+}
+
+// A Bundle is a single chunk containing a module and all modules it (transitively) requires
+//
+// Requires are resolved when the bundle is built using the same rules as require, and
+// requires which are not in the bundle (dynamic requires and modules which could not be
+// resolved) are passed on to the require function of the environment the bundle is run in.
+type Bundle struct {
+	Name     string    // The chunk name the bundle is loaded with
+	Entry    string    // The chunk name (path) of the entry module
+	Modules  []string  // The chunk names of the bundled modules, dependencies first
+	Source   string    // The Luau source code of the bundle
+	Warnings []Warning // Dynamic requires and requires that could not be resolved
+
+	lines []lineRange
+}
+
+// A module being bundled
+type module struct {
+	name     string
+	source   string
+	requires map[string]string // require string -> module name
+}
+
+// Build bundles the module at entry (an absolute path in vfs) with all modules it requires
+//
+// Requires are resolved using require.Resolve, which navigates vfs like SimpleRequirer does.
+func Build(vfs require.Vfs, entry string, opts *Options) (*Bundle, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	b := &Bundle{Name: opts.Name, Entry: entry}
+	if b.Name == "" {
+		b.Name = "bundle"
+	}
+
+	modules := make(map[string]*module)
+	var order []string // Post-order, so dependencies come first
+	var visit func(name string) error
+	visit = func(name string) error {
+		if _, ok := modules[name]; ok {
+			return nil
+		}
+		source, err := readFile(vfs, name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		mod := &module{name: name, source: source, requires: make(map[string]string)}
+		modules[name] = mod
+
+		for _, call := range scanRequires(source) {
+			if call.dynamic {
+				b.Warnings = append(b.Warnings, Warning{Chunk: name, Line: call.line, Message: "dynamic require is not bundled and is resolved at runtime"})
+				continue
+			}
+			if _, ok := mod.requires[call.path]; ok {
+				continue
+			}
+			resolution, err := require.Resolve(vfs, name, call.path)
+			if err != nil {
+				b.Warnings = append(b.Warnings, Warning{Chunk: name, Line: call.line, Message: fmt.Sprintf("require(%q) is not bundled: %v", call.path, err)})
+				continue
+			}
+			mod.requires[call.path] = resolution.Path
+			if err := visit(resolution.Path); err != nil {
+				return err
+			}
+		}
+		order = append(order, name)
+		return nil
+	}
+	if err := visit(entry); err != nil {
+		return nil, err
+	}
+
+	b.Modules = order
+	b.generate(modules, opts)
+	return b, nil
+}
+
+func readFile(vfs require.Vfs, name string) (string, error) {
+	file, err := vfs.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// The runtime of a bundle, requires are resolved through the require maps of the bundle
+const shim = `local __bundle_host_require = require
+local __bundle_modules = {}
+local __bundle_requires = {}
+local __bundle_loaded = {}
+local __bundle_loading = {}
+local function __bundle_require(from, path)
+	local name = __bundle_requires[from][path]
+	if name == nil then
+		if __bundle_host_require == nil then
+			error("could not find module '" .. tostring(path) .. "' in the bundle", 2)
+		end
+		return __bundle_host_require(path)
+	end
+	local loaded = __bundle_loaded[name]
+	if loaded then
+		return loaded[1]
+	end
+	if __bundle_loading[name] then
+		error("cyclic module dependency on " .. name, 2)
+	end
+	__bundle_loading[name] = true
+	-- The module is no longer loading if it errors, so requiring it again reports its error again
+	local ok, result = pcall(__bundle_modules[name], function(p)
+		return __bundle_require(name, p)
+	end)
+	__bundle_loading[name] = nil
+	if not ok then
+		error(result, 0)
+	end
+	__bundle_loaded[name] = { result }
+	return result
+end
+`
+
+// Helper used to load modules with their original chunk names
+const loadShim = `local function __bundle_load(name, source)
+	local fn, err = loadstring(source, "@" .. name)
+	if fn == nil then
+		error(err, 0)
+	end
+	return fn
+end
+`
+
+// Writes the source of the bundle and records the line ranges of the modules
+func (b *Bundle) generate(modules map[string]*module, opts *Options) {
+	var out strings.Builder
+	line := 1
+	write := func(s string) {
+		out.WriteString(s)
+		line += strings.Count(s, "\n")
+	}
+
+	write("-- Bundle of " + b.Entry + "\n")
+	write(shim)
+	if opts.PreserveChunkNames {
+		write(loadShim)
+	}
+
+	for _, name := range b.Modules {
+		mod := modules[name]
+		paths := make([]string, 0, len(mod.requires))
+		for path := range mod.requires {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		write("__bundle_requires[" + strconv.Quote(name) + "] = {")
+		for i, path := range paths {
+			if i > 0 {
+				write(", ")
+			}
+			write("[" + strconv.Quote(path) + "] = " + strconv.Quote(mod.requires[path]))
+		}
+		write("}\n")
+
+		// The first line of the module is kept on the first line of its function (or string),
+		// so the lines of the module map to consecutive lines of the bundle
+		source := strings.TrimSuffix(mod.source, "\n")
+		var start int
+		if opts.PreserveChunkNames {
+			// The loaded chunk receives the require shim as its first argument. The newline
+			// after the opening bracket is not part of the string, so the module starts on
+			// the line after it.
+			level := longBracketFreeLevel(source)
+			write("__bundle_modules[" + strconv.Quote(name) + "] = __bundle_load(" + strconv.Quote(name) + ", [" + level + "[\n")
+			start = line
+			write("local require = ...; ")
+			write(source)
+			write("\n]" + level + "])\n")
+		} else {
+			start = line
+			write("__bundle_modules[" + strconv.Quote(name) + "] = function(require) ")
+			write(source)
+			write("\nend\n")
+		}
+		b.lines = append(b.lines, lineRange{chunk: name, start: start, lines: strings.Count(source, "\n") + 1})
+	}
+
+	write("__bundle_requires[\"\"] = {[\"\"] = " + strconv.Quote(b.Entry) + "}\n")
+	write("return __bundle_require(\"\", \"\")\n")
+	b.Source = out.String()
+}
+
+// Returns the equals signs of a long bracket level which does not occur in source
+func longBracketFreeLevel(source string) string {
+	level := ""
+	for strings.Contains(source, "]"+level+"]") {
+		level += "="
+	}
+	return level
+}
+
+// Position maps a line of the bundle to the chunk name and line of the module it belongs to
+func (b *Bundle) Position(line int) (string, int, bool) {
+	for _, r := range b.lines {
+		if line >= r.start && line < r.start+r.lines {
+			return r.chunk, line - r.start + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// TranslateError rewrites positions in the bundle (e.g. "bundle:42:") in an error message or
+// traceback to positions in the bundled modules
+func (b *Bundle) TranslateError(msg string) string {
+	name := regexp.QuoteMeta(b.Name)
+	re := regexp.MustCompile(`(?:\[string "` + name + `"\]|` + name + `):(\d+):`)
+	return re.ReplaceAllStringFunc(msg, func(match string) string {
+		groups := re.FindStringSubmatch(match)
+		line, err := strconv.Atoi(groups[1])
+		if err != nil {
+			return match
+		}
+		if chunk, moduleLine, ok := b.Position(line); ok {
+			return chunk + ":" + strconv.Itoa(moduleLine) + ":"
+		}
+		return match
+	})
+}
+
+// Load loads the bundle as a chunk named b.Name with the given environment (may be nil)
+func (b *Bundle) Load(lua *vm.Lua, env *vm.LuaTable) (*vm.LuaFunction, error) {
+	return lua.LoadChunk(vm.ChunkOpts{
+		Name: b.Name,
+		Code: b.Source,
+		Mode: vm.ChunkModeText,
+		Env:  env,
+	})
+}
+
+// Bytecode compiles the bundle to bytecode, which can be loaded using vm.ChunkModeBinary
+func (b *Bundle) Bytecode(lua *vm.Lua, opts *vm.CompilerOpts) ([]byte, error) {
+	return lua.Compile(b.Source, opts)
+}
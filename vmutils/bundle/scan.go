@@ -0,0 +1,201 @@
+package bundle
+
+import (
+	"strings"
+)
+
+// A call of require found in the source of a module
+type requireCall struct {
+	path    string // The require string, if it is a string literal
+	line    int    // 1-based
+	dynamic bool   // Whether the argument is not a single string literal
+}
+
+// Scans Luau source code for calls of require, skipping comments and strings
+func scanRequires(src string) []requireCall {
+	var calls []requireCall
+	line := 1
+	prev := -1 // The index of the previous significant (non-whitespace) byte
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '-' && strings.HasPrefix(src[i:], "--"):
+			end := skipComment(src, i)
+			line += strings.Count(src[i:end], "\n")
+			i = end
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			end := skipQuoted(src, i)
+			line += strings.Count(src[i:end], "\n")
+			i = end
+		case c == '[' && longBracketLevel(src, i) >= 0:
+			end := skipLongBracket(src, i)
+			line += strings.Count(src[i:end], "\n")
+			i = end
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			if src[start:i] == "require" && !isFieldAccess(src, prev) {
+				calls = append(calls, parseRequireCall(src, i, line))
+			}
+		default:
+			i++
+		}
+		prev = i - 1
+	}
+	return calls
+}
+
+// Returns whether the byte at prev makes the following identifier a field or method name
+// (a.require or a:require, but not a .. require)
+func isFieldAccess(src string, prev int) bool {
+	if prev < 0 {
+		return false
+	}
+	switch src[prev] {
+	case ':':
+		return true
+	case '.':
+		return prev == 0 || src[prev-1] != '.'
+	}
+	return false
+}
+
+// Parses the arguments of a require call starting at i (right after the require identifier)
+func parseRequireCall(src string, i, line int) requireCall {
+	call := requireCall{line: line, dynamic: true}
+	i = skipSpace(src, i)
+	if i >= len(src) {
+		return call
+	}
+
+	switch c := src[i]; {
+	case c == '"' || c == '\'':
+		// Call without parentheses: require "x"
+		call.path, call.dynamic = unquote(src[i:skipQuoted(src, i)]), false
+	case c == '(':
+		i = skipSpace(src, i+1)
+		if i >= len(src) || (src[i] != '"' && src[i] != '\'') {
+			return call
+		}
+		end := skipQuoted(src, i)
+		path := unquote(src[i:end])
+		if j := skipSpace(src, end); j < len(src) && src[j] == ')' {
+			call.path, call.dynamic = path, false
+		}
+	}
+	return call
+}
+
+func skipSpace(src string, i int) int {
+	for i < len(src) && (src[i] == ' ' || src[i] == '\t' || src[i] == '\r' || src[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// Returns the end of the comment starting at i
+func skipComment(src string, i int) int {
+	if longBracketLevel(src, i+2) >= 0 {
+		return skipLongBracket(src, i+2)
+	}
+	end := strings.IndexByte(src[i:], '\n')
+	if end < 0 {
+		return len(src)
+	}
+	return i + end
+}
+
+// Returns the end of the quoted string starting at i
+func skipQuoted(src string, i int) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		case '\n':
+			if quote != '`' {
+				return j // Unterminated string
+			}
+		}
+	}
+	return len(src)
+}
+
+// Returns the level of the long bracket ([[, [=[, ...) starting at i, or -1 if there is none
+func longBracketLevel(src string, i int) int {
+	if i >= len(src) || src[i] != '[' {
+		return -1
+	}
+	level := 0
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '=':
+			level++
+		case '[':
+			return level
+		default:
+			return -1
+		}
+	}
+	return -1
+}
+
+// Returns the end of the long bracket string starting at i
+func skipLongBracket(src string, i int) int {
+	level := longBracketLevel(src, i)
+	closing := "]" + strings.Repeat("=", level) + "]"
+	end := strings.Index(src[i+level+2:], closing)
+	if end < 0 {
+		return len(src)
+	}
+	return i + level + 2 + end + len(closing)
+}
+
+// Returns the value of a quoted string literal, decoding common escapes
+func unquote(literal string) string {
+	if len(literal) < 2 {
+		return ""
+	}
+	body := literal[1 : len(literal)-1]
+	if !strings.Contains(body, "\\") {
+		return body
+	}
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		if body[i] != '\\' || i+1 >= len(body) {
+			b.WriteByte(body[i])
+			continue
+		}
+		i++
+		switch body[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(body[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,805 @@
+package vmutils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/koeng101/gluau/vm"
+)
+
+var (
+	valueType       = reflect.TypeOf((*vm.Value)(nil)).Elem()
+	errorType       = reflect.TypeOf((*error)(nil)).Elem()
+	callbackLuaType = reflect.TypeOf((*vm.CallbackLua)(nil))
+	luaTableType    = reflect.TypeOf((*vm.LuaTable)(nil))
+	luaFunctionType = reflect.TypeOf((*vm.LuaFunction)(nil))
+	luaStringType   = reflect.TypeOf((*vm.LuaString)(nil))
+	luaUserDataType = reflect.TypeOf((*vm.LuaUserData)(nil))
+	luaBufferType   = reflect.TypeOf((*vm.LuaBuffer)(nil))
+	luaThreadType   = reflect.TypeOf((*vm.LuaThread)(nil))
+	bytesType       = reflect.TypeOf([]byte(nil))
+	vectorType      = reflect.TypeOf([3]float32{})
+)
+
+// A ConversionError is returned when a Luau value cannot be converted to
+// the requested Go type (or vice versa)
+type ConversionError struct {
+	Expected string // The expected (Luau) type
+	Got      string // The actual (Luau) type
+}
+
+func (e *ConversionError) Error() string {
+	return e.Expected + " expected, got " + e.Got
+}
+
+// Returns the Luau name of the type of a value, as used in error messages
+func luauTypeName(v vm.Value) string {
+	if v == nil {
+		return "nil"
+	}
+	switch v.Type() {
+	case vm.LuaValueInteger:
+		return "number"
+	case vm.LuaValueUserData:
+		if name := userDataTypeName(v.(*vm.ValueUserData).Value()); name != "" {
+			return name
+		}
+		return "userdata"
+	default:
+		return v.Type().String()
+	}
+}
+
+// Returns the __type of a userdata or "" if it has none
+func userDataTypeName(ud *vm.LuaUserData) string {
+	mt, err := ud.Metatable()
+	if err != nil || mt == nil {
+		return ""
+	}
+	defer mt.Close()
+	typ, err := mt.RawGet(vm.GoString("__type"))
+	if err != nil {
+		return ""
+	}
+	defer typ.Close()
+	if s, ok := typ.(*vm.ValueString); ok {
+		return s.Value().String()
+	}
+	return ""
+}
+
+// Returns the Luau type name expected for a Go type, as used in error messages
+func expectedTypeName(t reflect.Type) string {
+	if b := lookupBoundType(t); b != nil {
+		return b.typeName()
+	}
+
+	switch t {
+	case valueType:
+		return "any"
+	case luaTableType:
+		return "table"
+	case luaFunctionType:
+		return "function"
+	case luaStringType, bytesType:
+		return "string"
+	case luaUserDataType:
+		return "userdata"
+	case luaBufferType:
+		return "buffer"
+	case luaThreadType:
+		return "thread"
+	case vectorType:
+		return "vector"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		return "table"
+	case reflect.Func:
+		return "function"
+	case reflect.Pointer:
+		return expectedTypeName(t.Elem())
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "any"
+		}
+		return "userdata"
+	default:
+		return t.String()
+	}
+}
+
+// ToValue converts a Go value to a Luau value
+//
+// Supported are booleans, integers, floats, strings, []byte (as a string), slices and arrays
+// (as sequential tables), maps and structs (as tables), pointers to types bound using BindType
+// (as userdata) and errors (as their message). vm.Value's and Lua handles (e.g. *vm.LuaTable)
+// are passed through as is (and hence will have their ownership taken).
+func ToValue(lua *vm.Lua, v any) (vm.Value, error) {
+	return toValue(lua, reflect.ValueOf(v))
+}
+
+// FromValue converts a Luau value to a Go value of type T
+//
+// This supports the same types as ToValue. Converting to `any` returns
+// the "natural" Go representation of the value (e.g. float64 for numbers,
+// []any or map[any]any for tables). Unlike function arguments, v is not closed.
+func FromValue[T any](v vm.Value) (T, error) {
+	var zero T
+	rv, err := fromValue(v, reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return zero, err
+	}
+	out, _ := rv.Interface().(T) // Nil interfaces convert to the zero value
+	return out, nil
+}
+
+func toValue(lua *vm.Lua, rv reflect.Value) (vm.Value, error) {
+	return (&goConverter{lua: lua}).toValue(rv)
+}
+
+// A goConverter holds the state of a conversion from a Go value to a Luau value
+type goConverter struct {
+	lua      *vm.Lua
+	visiting map[goRef]bool // pointers, maps and slices that are being converted, to detect cycles
+}
+
+// Identifies the memory a Go pointer, map or slice refers to
+type goRef struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// Marks the memory referred to by rv as being converted until done is called
+//
+// An error is returned if it is already being converted, i.e. the value is cyclic.
+func (c *goConverter) enter(rv reflect.Value) (done func(), err error) {
+	ref := goRef{ptr: rv.Pointer(), typ: rv.Type()}
+	if c.visiting[ref] {
+		return nil, fmt.Errorf("cannot convert cyclic Go value of type %s to a Luau value", rv.Type())
+	}
+	if c.visiting == nil {
+		c.visiting = make(map[goRef]bool)
+	}
+	c.visiting[ref] = true
+	return func() {
+		delete(c.visiting, ref)
+	}, nil
+}
+
+func (c *goConverter) toValue(rv reflect.Value) (vm.Value, error) {
+	lua := c.lua
+	if !rv.IsValid() {
+		return vm.NewValueNil(), nil
+	}
+
+	if rv.CanInterface() {
+		switch v := rv.Interface().(type) {
+		case vm.Value:
+			if v == nil {
+				return vm.NewValueNil(), nil
+			}
+			return v, nil
+		case *vm.LuaTable:
+			return nilOr(v == nil, func() vm.Value { return v.ToValue() }), nil
+		case *vm.LuaFunction:
+			return nilOr(v == nil, func() vm.Value { return v.ToValue() }), nil
+		case *vm.LuaString:
+			return nilOr(v == nil, func() vm.Value { return v.ToValue() }), nil
+		case *vm.LuaUserData:
+			return nilOr(v == nil, func() vm.Value { return v.ToValue() }), nil
+		case *vm.LuaBuffer:
+			return nilOr(v == nil, func() vm.Value { return v.ToValue() }), nil
+		case *vm.LuaThread:
+			return nilOr(v == nil, func() vm.Value { return v.ToValue() }), nil
+		case error:
+			if rv.Kind() == reflect.Pointer && rv.IsNil() {
+				return vm.NewValueNil(), nil
+			}
+			return vm.GoString(v.Error()), nil
+		case []byte:
+			if v == nil {
+				return vm.NewValueNil(), nil
+			}
+			s, err := lua.CreateStringBytes(v)
+			if err != nil {
+				return nil, err
+			}
+			return s.ToValue(), nil
+		case [3]float32:
+			return vm.NewValueVector(v[0], v[1], v[2]), nil
+		}
+	}
+
+	// Bound types are converted to userdata
+	if b := lookupBoundType(rv.Type()); b != nil {
+		if rv.IsNil() {
+			return vm.NewValueNil(), nil
+		}
+		ud, err := b.createAny(lua, rv.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return ud.ToValue(), nil
+	}
+	if rv.Kind() == reflect.Struct {
+		if b := lookupBoundType(reflect.PointerTo(rv.Type())); b != nil {
+			ptr := reflect.New(rv.Type())
+			ptr.Elem().Set(rv)
+			ud, err := b.createAny(lua, ptr.Interface())
+			if err != nil {
+				return nil, err
+			}
+			return ud.ToValue(), nil
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return vm.NewValueBoolean(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return vm.NewValueInteger(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return vm.NewValueNumber(float64(u)), nil
+		}
+		return vm.NewValueInteger(int64(u)), nil
+	case reflect.Float32, reflect.Float64:
+		return vm.NewValueNumber(rv.Float()), nil
+	case reflect.String:
+		return vm.GoString(rv.String()), nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return vm.NewValueNil(), nil
+		}
+		if rv.Kind() == reflect.Pointer {
+			done, err := c.enter(rv)
+			if err != nil {
+				return nil, err
+			}
+			defer done()
+		}
+		return c.toValue(rv.Elem())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return vm.NewValueNil(), nil
+		}
+		if rv.Kind() == reflect.Slice && rv.Len() > 0 {
+			done, err := c.enter(rv)
+			if err != nil {
+				return nil, err
+			}
+			defer done()
+		}
+		tab, err := lua.CreateTableWithCapacity(rv.Len(), 0)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < rv.Len(); i++ {
+			elem, err := c.toValue(rv.Index(i))
+			if err != nil {
+				tab.Close()
+				return nil, err
+			}
+			if err := tab.RawSet(vm.NewValueInteger(int64(i+1)), elem); err != nil {
+				tab.Close()
+				return nil, err
+			}
+		}
+		return tab.ToValue(), nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return vm.NewValueNil(), nil
+		}
+		done, err := c.enter(rv)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+		tab, err := lua.CreateTableWithCapacity(0, rv.Len())
+		if err != nil {
+			return nil, err
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := c.toValue(iter.Key())
+			if err != nil {
+				tab.Close()
+				return nil, err
+			}
+			value, err := c.toValue(iter.Value())
+			if err != nil {
+				key.Close()
+				tab.Close()
+				return nil, err
+			}
+			if err := tab.RawSet(key, value); err != nil {
+				tab.Close()
+				return nil, err
+			}
+		}
+		return tab.ToValue(), nil
+	case reflect.Struct:
+		tab, err := lua.CreateTable()
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range structFields(rv.Type(), nil) {
+			fv, err := rv.FieldByIndexErr(field.index)
+			if err != nil {
+				continue // Nil embedded pointer
+			}
+			value, err := c.toValue(fv)
+			if err != nil {
+				tab.Close()
+				return nil, err
+			}
+			if err := tab.RawSet(vm.GoString(field.name), value); err != nil {
+				tab.Close()
+				return nil, err
+			}
+		}
+		return tab.ToValue(), nil
+	default:
+		return nil, fmt.Errorf("cannot convert Go value of type %s to a Luau value", rv.Type())
+	}
+}
+
+func nilOr(isNil bool, f func() vm.Value) vm.Value {
+	if isNil {
+		return vm.NewValueNil()
+	}
+	return f()
+}
+
+// Converts a Luau value to a Go value of type t
+//
+// If the conversion fails, the Luau values retained by the partially converted value are closed.
+func fromValue(v vm.Value, t reflect.Type) (reflect.Value, error) {
+	c := &converter{}
+	out, err := c.fromValue(v, t)
+	if err != nil {
+		c.closeRetained()
+		return reflect.Value{}, err
+	}
+	return out, nil
+}
+
+// A converter holds the state of a conversion from a Luau value to a Go value
+type converter struct {
+	visiting map[uint64]bool // tables that are being converted, to detect cyclic tables
+	retained []vm.Value      // nested values retained by the converted value
+}
+
+// Marks a table as being converted until done is called
+//
+// Returns an error if the table is already being converted (i.e. it references itself), as
+// converting it would never end.
+func (c *converter) enter(tab *vm.LuaTable) (done func(), err error) {
+	ptr := tab.Pointer()
+	if c.visiting[ptr] {
+		return nil, &ConversionError{Expected: "non-cyclic table", Got: "cyclic table"}
+	}
+	if c.visiting == nil {
+		c.visiting = make(map[uint64]bool)
+	}
+	c.visiting[ptr] = true
+	return func() {
+		delete(c.visiting, ptr)
+	}, nil
+}
+
+// Converts a nested value (e.g. an element of a table), closing it unless it is retained
+func (c *converter) nested(v vm.Value, t reflect.Type) (reflect.Value, error) {
+	out, err := c.fromValue(v, t)
+	if err != nil {
+		v.Close()
+		return reflect.Value{}, err
+	}
+	if closeUnlessRetained(v, out) {
+		c.retained = append(c.retained, v)
+	}
+	return out, nil
+}
+
+// Closes the nested values retained by a conversion that failed
+func (c *converter) closeRetained() {
+	for _, v := range c.retained {
+		v.Close()
+	}
+	c.retained = nil
+}
+
+func (c *converter) fromValue(v vm.Value, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		v = vm.NewValueNil()
+	}
+
+	mismatch := func() (reflect.Value, error) {
+		return reflect.Value{}, &ConversionError{Expected: expectedTypeName(t), Got: luauTypeName(v)}
+	}
+	// Numbers without an integer representation are reported as such rather than as
+	// "number expected, got number"
+	integerMismatch := func() (reflect.Value, error) {
+		if _, ok := v.(*vm.ValueNumber); ok {
+			return reflect.Value{}, &ConversionError{Expected: "integer", Got: "number"}
+		}
+		return mismatch()
+	}
+
+	switch t {
+	case valueType:
+		out := reflect.New(t).Elem()
+		out.Set(reflect.ValueOf(v))
+		return out, nil
+	case luaTableType:
+		if tv, ok := v.(*vm.ValueTable); ok {
+			return reflect.ValueOf(tv.Value()), nil
+		}
+		return mismatch()
+	case luaFunctionType:
+		if fv, ok := v.(*vm.ValueFunction); ok {
+			return reflect.ValueOf(fv.Value()), nil
+		}
+		return mismatch()
+	case luaStringType:
+		if sv, ok := v.(*vm.ValueString); ok {
+			return reflect.ValueOf(sv.Value()), nil
+		}
+		return mismatch()
+	case luaUserDataType:
+		if uv, ok := v.(*vm.ValueUserData); ok {
+			return reflect.ValueOf(uv.Value()), nil
+		}
+		return mismatch()
+	case luaBufferType:
+		if bv, ok := v.(*vm.ValueBuffer); ok {
+			return reflect.ValueOf(bv.Value()), nil
+		}
+		return mismatch()
+	case luaThreadType:
+		if tv, ok := v.(*vm.ValueThread); ok {
+			return reflect.ValueOf(tv.Value()), nil
+		}
+		return mismatch()
+	case vectorType:
+		if vv, ok := v.(*vm.ValueVector); ok {
+			return reflect.ValueOf(vv.Value()), nil
+		}
+		return mismatch()
+	}
+
+	// Userdata of bound types (or types implementing an interface)
+	if uv, ok := v.(*vm.ValueUserData); ok {
+		if t.Kind() == reflect.Pointer || (t.Kind() == reflect.Interface && t.NumMethod() > 0) {
+			data, err := uv.Value().AssociatedData()
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if data != nil && reflect.TypeOf(data).AssignableTo(t) {
+				out := reflect.New(t).Elem()
+				out.Set(reflect.ValueOf(data))
+				return out, nil
+			}
+		}
+		if t.Kind() == reflect.Struct {
+			data, err := uv.Value().AssociatedData()
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if data != nil && reflect.TypeOf(data) == reflect.PointerTo(t) {
+				return reflect.ValueOf(data).Elem(), nil
+			}
+		}
+	}
+	if lookupBoundType(t) != nil {
+		if _, ok := v.(*vm.ValueNil); ok {
+			return reflect.Zero(t), nil
+		}
+		return mismatch()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if bv, ok := v.(*vm.ValueBoolean); ok {
+			return reflect.ValueOf(bv.Value()).Convert(t), nil
+		}
+		return mismatch()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := integerOf(v)
+		if !ok {
+			return integerMismatch()
+		}
+		out := reflect.New(t).Elem()
+		if out.OverflowInt(n) {
+			return reflect.Value{}, fmt.Errorf("number %d is out of range for %s", n, t)
+		}
+		out.SetInt(n)
+		return out, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := integerOf(v)
+		if !ok {
+			return integerMismatch()
+		}
+		out := reflect.New(t).Elem()
+		if n < 0 || out.OverflowUint(uint64(n)) {
+			return reflect.Value{}, fmt.Errorf("number %d is out of range for %s", n, t)
+		}
+		out.SetUint(uint64(n))
+		return out, nil
+	case reflect.Float32, reflect.Float64:
+		switch nv := v.(type) {
+		case *vm.ValueNumber:
+			return reflect.ValueOf(nv.Value()).Convert(t), nil
+		case *vm.ValueInteger:
+			return reflect.ValueOf(float64(nv.Value())).Convert(t), nil
+		}
+		return mismatch()
+	case reflect.String:
+		// Like Luau, numbers are coerced to strings
+		switch sv := v.(type) {
+		case *vm.ValueString:
+			return reflect.ValueOf(sv.Value().String()).Convert(t), nil
+		case vm.GoString:
+			return reflect.ValueOf(string(sv)).Convert(t), nil
+		case *vm.ValueInteger:
+			return reflect.ValueOf(strconv.FormatInt(sv.Value(), 10)).Convert(t), nil
+		case *vm.ValueNumber:
+			return reflect.ValueOf(strconv.FormatFloat(sv.Value(), 'g', 14, 64)).Convert(t), nil
+		}
+		return mismatch()
+	case reflect.Pointer:
+		if _, ok := v.(*vm.ValueNil); ok {
+			return reflect.Zero(t), nil
+		}
+		elem, err := c.fromValue(v, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return mismatch()
+		}
+		natural, err := c.naturalValue(v)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out := reflect.New(t).Elem()
+		if natural != nil {
+			out.Set(reflect.ValueOf(natural))
+		}
+		return out, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			switch bv := v.(type) {
+			case *vm.ValueString:
+				return reflect.ValueOf(bv.Value().Bytes()).Convert(t), nil
+			case vm.GoString:
+				return reflect.ValueOf([]byte(bv)).Convert(t), nil
+			case *vm.ValueBuffer:
+				return reflect.ValueOf(bv.Value().Bytes()).Convert(t), nil
+			}
+		}
+		tv, ok := v.(*vm.ValueTable)
+		if !ok {
+			return mismatch()
+		}
+		done, err := c.enter(tv.Value())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		defer done()
+		out := reflect.MakeSlice(t, 0, int(tv.Value().RawLen()))
+		err = tv.Value().ForEachValue(func(elem vm.Value) error {
+			ev, err := c.nested(elem, t.Elem())
+			if err != nil {
+				return err
+			}
+			out = reflect.Append(out, ev)
+			return nil
+		})
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return out, nil
+	case reflect.Array:
+		tv, ok := v.(*vm.ValueTable)
+		if !ok {
+			return mismatch()
+		}
+		done, err := c.enter(tv.Value())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		defer done()
+		out := reflect.New(t).Elem()
+		i := 0
+		err = tv.Value().ForEachValue(func(elem vm.Value) error {
+			if i >= t.Len() {
+				elem.Close()
+				return fmt.Errorf("table has more than %d elements", t.Len())
+			}
+			ev, err := c.nested(elem, t.Elem())
+			if err != nil {
+				return err
+			}
+			out.Index(i).Set(ev)
+			i++
+			return nil
+		})
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return out, nil
+	case reflect.Map:
+		tv, ok := v.(*vm.ValueTable)
+		if !ok {
+			return mismatch()
+		}
+		done, err := c.enter(tv.Value())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		defer done()
+		out := reflect.MakeMap(t)
+		err = tv.Value().ForEach(func(key, value vm.Value) error {
+			keyType := luauTypeName(key)
+			kv, err := c.nested(key, t.Key())
+			if err != nil {
+				value.Close()
+				return err
+			}
+			// Keys converted to interfaces may hold unhashable values (e.g. a table key as a map)
+			if !kv.Comparable() {
+				value.Close()
+				return &ConversionError{Expected: "hashable key", Got: keyType}
+			}
+			vv, err := c.nested(value, t.Elem())
+			if err != nil {
+				return err
+			}
+			out.SetMapIndex(kv, vv)
+			return nil
+		})
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return out, nil
+	case reflect.Struct:
+		tv, ok := v.(*vm.ValueTable)
+		if !ok {
+			return mismatch()
+		}
+		done, err := c.enter(tv.Value())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		defer done()
+		out := reflect.New(t).Elem()
+		for _, field := range structFields(t, nil) {
+			fv, err := tv.Value().RawGet(vm.GoString(field.name))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if _, ok := fv.(*vm.ValueNil); ok {
+				continue
+			}
+			converted, err := c.nested(fv, field.typ)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field '%s': %w", field.name, err)
+			}
+			dst, err := out.FieldByIndexErr(field.index)
+			if err != nil {
+				continue // Nil embedded pointer
+			}
+			dst.Set(converted)
+		}
+		return out, nil
+	default:
+		return reflect.Value{}, fmt.Errorf("cannot convert Luau value to Go value of type %s", t)
+	}
+}
+
+// Returns the value of a number as an integer if it is integral
+func integerOf(v vm.Value) (int64, bool) {
+	switch nv := v.(type) {
+	case *vm.ValueInteger:
+		return nv.Value(), true
+	case *vm.ValueNumber:
+		f := nv.Value()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(f), true
+	}
+	return 0, false
+}
+
+// Returns the "natural" Go representation of a Luau value
+func (c *converter) naturalValue(v vm.Value) (any, error) {
+	switch nv := v.(type) {
+	case *vm.ValueNil:
+		return nil, nil
+	case *vm.ValueBoolean:
+		return nv.Value(), nil
+	case *vm.ValueInteger:
+		return nv.Value(), nil
+	case *vm.ValueNumber:
+		return nv.Value(), nil
+	case *vm.ValueVector:
+		return nv.Value(), nil
+	case *vm.ValueString:
+		return nv.Value().String(), nil
+	case vm.GoString:
+		return string(nv), nil
+	case *vm.ValueBuffer:
+		return nv.Value().Bytes(), nil
+	case *vm.ValueUserData:
+		return nv.Value().AssociatedData()
+	case *vm.ValueFunction:
+		return nv.Value(), nil
+	case *vm.ValueThread:
+		return nv.Value(), nil
+	case *vm.ValueTable:
+		tab := nv.Value()
+		// Sequences are converted to slices, everything else to maps
+		n := tab.RawLen()
+		count := uint64(0)
+		isSeq := true
+		err := tab.ForEach(func(key, value vm.Value) error {
+			count++
+			if idx, ok := integerOf(key); !ok || idx < 1 || uint64(idx) > n {
+				isSeq = false
+			}
+			key.Close()
+			value.Close()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if isSeq && count == n && n > 0 {
+			out, err := c.fromValue(v, reflect.TypeOf([]any(nil)))
+			if err != nil {
+				return nil, err
+			}
+			return out.Interface(), nil
+		}
+		out, err := c.fromValue(v, reflect.TypeOf(map[any]any(nil)))
+		if err != nil {
+			return nil, err
+		}
+		return out.Interface(), nil
+	default:
+		return nil, errors.New("cannot convert Luau value of type " + v.Type().String() + " to a Go value")
+	}
+}
+
+// Closes a Luau value after conversion unless the converted Go value holds on to it,
+// returning whether it was retained
+func closeUnlessRetained(v vm.Value, converted reflect.Value) bool {
+	if converted.IsValid() && converted.CanInterface() {
+		switch converted.Interface().(type) {
+		case vm.Value, *vm.LuaTable, *vm.LuaFunction, *vm.LuaString, *vm.LuaUserData, *vm.LuaBuffer, *vm.LuaThread:
+			return true
+		}
+	}
+	v.Close()
+	return false
+}
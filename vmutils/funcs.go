@@ -0,0 +1,201 @@
+package vmutils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/koeng101/gluau/vm"
+)
+
+// WrapFunc creates a Lua function calling fn, which must be a Go function
+//
+// Arguments and return values are converted using FromValue and ToValue, with conversion
+// errors reported Luau-style (e.g. "bad argument #2 to 'append' (number expected, got string)").
+// Variadic functions accept any number of trailing arguments and missing arguments are passed
+// as nil to pointer, interface, slice and map parameters, making such trailing parameters
+// optional. A trailing error return value is raised as a Luau error and a leading
+// *vm.CallbackLua parameter is passed the calling Lua state.
+//
+// The name used in error messages is the name of the Go function, use WrapNamedFunc to set it.
+func WrapFunc(lua *vm.Lua, fn any) (*vm.LuaFunction, error) {
+	return WrapNamedFunc(lua, funcName(fn), fn)
+}
+
+// WrapNamedFunc is like WrapFunc, but uses name in error messages
+func WrapNamedFunc(lua *vm.Lua, name string, fn any) (*vm.LuaFunction, error) {
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func || fnValue.IsNil() {
+		return nil, errors.New("WrapFunc expects a non-nil function")
+	}
+
+	return lua.CreateFunction(func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		return callReflect(funcVm, fnValue, name, reflect.Value{}, args)
+	})
+}
+
+// A FuncOption configures a function created by Func0, Func1, Func2, Func3 or FuncVariadic
+type FuncOption func(*funcOptions)
+
+type funcOptions struct {
+	name string
+}
+
+// WithName sets the name used for the function in error messages (e.g. "bad argument #1 to
+// 'name'"), which defaults to the name of the Go function or "?" for closures
+func WithName(name string) FuncOption {
+	return func(o *funcOptions) {
+		o.name = name
+	}
+}
+
+// Returns the name of fn used in error messages, taking the options into account
+func funcNameOf(fn any, opts []FuncOption) string {
+	o := funcOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.name != "" {
+		return o.name
+	}
+	return funcName(fn)
+}
+
+// Func0 creates a Lua function calling a Go function without arguments (see WrapFunc)
+//
+// Use WrapFunc for functions with more arguments or multiple return values. As a function
+// without arguments cannot fail to convert them, the name is only used if the result cannot
+// be converted.
+func Func0[R any](lua *vm.Lua, fn func() (R, error), opts ...FuncOption) (*vm.LuaFunction, error) {
+	name := funcNameOf(fn, opts)
+	return lua.CreateFunction(func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		closeExtraArgs(args, 0)
+		r, err := fn()
+		return resultOf(funcVm, name, r, err)
+	})
+}
+
+// Func1 creates a Lua function calling a Go function with one argument (see WrapFunc)
+func Func1[A, R any](lua *vm.Lua, fn func(A) (R, error), opts ...FuncOption) (*vm.LuaFunction, error) {
+	name := funcNameOf(fn, opts)
+	return lua.CreateFunction(func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		a, err := argAt[A](args, 0, name)
+		if err != nil {
+			return nil, err
+		}
+		closeExtraArgs(args, 1)
+		r, err := fn(a)
+		return resultOf(funcVm, name, r, err)
+	})
+}
+
+// Func2 creates a Lua function calling a Go function with two arguments (see WrapFunc)
+func Func2[A, B, R any](lua *vm.Lua, fn func(A, B) (R, error), opts ...FuncOption) (*vm.LuaFunction, error) {
+	name := funcNameOf(fn, opts)
+	return lua.CreateFunction(func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		a, err := argAt[A](args, 0, name)
+		if err != nil {
+			return nil, err
+		}
+		b, err := argAt[B](args, 1, name)
+		if err != nil {
+			return nil, err
+		}
+		closeExtraArgs(args, 2)
+		r, err := fn(a, b)
+		return resultOf(funcVm, name, r, err)
+	})
+}
+
+// Func3 creates a Lua function calling a Go function with three arguments (see WrapFunc)
+func Func3[A, B, C, R any](lua *vm.Lua, fn func(A, B, C) (R, error), opts ...FuncOption) (*vm.LuaFunction, error) {
+	name := funcNameOf(fn, opts)
+	return lua.CreateFunction(func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		a, err := argAt[A](args, 0, name)
+		if err != nil {
+			return nil, err
+		}
+		b, err := argAt[B](args, 1, name)
+		if err != nil {
+			return nil, err
+		}
+		c, err := argAt[C](args, 2, name)
+		if err != nil {
+			return nil, err
+		}
+		closeExtraArgs(args, 3)
+		r, err := fn(a, b, c)
+		return resultOf(funcVm, name, r, err)
+	})
+}
+
+// FuncVariadic creates a Lua function calling a Go function taking any number of arguments of type A (see WrapFunc)
+func FuncVariadic[A, R any](lua *vm.Lua, fn func(...A) (R, error), opts ...FuncOption) (*vm.LuaFunction, error) {
+	name := funcNameOf(fn, opts)
+	return lua.CreateFunction(func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		rest := make([]A, 0, len(args))
+		for i := range args {
+			a, err := argAt[A](args, i, name)
+			if err != nil {
+				return nil, err
+			}
+			rest = append(rest, a)
+		}
+		r, err := fn(rest...)
+		return resultOf(funcVm, name, r, err)
+	})
+}
+
+// Returns the argument at index i converted to A
+func argAt[A any](args []vm.Value, i int, name string) (A, error) {
+	var zero A
+	converted, err := convertArg(args, i, reflect.TypeOf((*A)(nil)).Elem())
+	if err != nil {
+		closeExtraArgs(args, 0) // The function is not called, so no argument is retained
+		return zero, badArgumentError(i+1, name, err)
+	}
+	out, _ := converted.Interface().(A) // Nil interfaces convert to the zero value
+	return out, nil
+}
+
+// Converts the result of a wrapped function to Luau values
+func resultOf[R any](funcVm *vm.CallbackLua, name string, r R, err error) ([]vm.Value, error) {
+	if err != nil {
+		return nil, err
+	}
+	v, err := ToValue(funcVm.MainState(), r)
+	if err != nil {
+		return nil, fmt.Errorf("bad result of '%s' (%w)", name, err)
+	}
+	return []vm.Value{v}, nil
+}
+
+// Closes the arguments after the first n (which are not used by the function)
+func closeExtraArgs(args []vm.Value, n int) {
+	for i := n; i < len(args); i++ {
+		args[i].Close()
+	}
+}
+
+// Names of anonymous functions as reported by the runtime (e.g. main.main.func1)
+var anonymousFuncName = regexp.MustCompile(`^func\d+$`)
+
+// Returns the name of a Go function for use in error messages, or "?" if it is anonymous
+func funcName(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "?"
+	}
+	name := f.Name()
+	name = strings.TrimSuffix(name, "-fm") // Method values
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	if name == "" || anonymousFuncName.MatchString(name) {
+		return "?"
+	}
+	return name
+}
@@ -0,0 +1,410 @@
+package vmutils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/koeng101/gluau/vm"
+	"github.com/koeng101/gluau/vmutils/require"
+)
+
+// A Library is a declarative description of a Luau library (a table of functions,
+// constants and sub-tables) which can be installed into any number of Lua VMs
+type Library struct {
+	// The name of the library (the global it is installed as, or the module name when required)
+	Name string
+
+	// Functions of the library. Values of type vm.FunctionFn (or a function with the same
+	// signature) are used as-is, any other Go function is wrapped using WrapNamedFunc.
+	// *vm.LuaFunction values are cloned, so the library keeps its own handle to them.
+	Functions map[string]any
+
+	// Constants of the library, converted using ToValue
+	Constants map[string]any
+
+	// Sub-tables of the library, keyed by their name in the library table
+	Tables map[string]*Library
+
+	// If set, Install installs a proxy table and only builds the library the first time it is indexed
+	//
+	// Lazily installed libraries cannot be iterated using pairs. Libraries required through a
+	// LibraryRequirer are always only built when first required.
+	Lazy bool
+}
+
+// Build creates the (read-only) table of the library in a Lua VM
+func (lib *Library) Build(lua *vm.Lua) (*vm.LuaTable, error) {
+	table, err := lua.CreateTable()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lib.fill(lua, table); err != nil {
+		table.Close()
+		return nil, err
+	}
+
+	table.SetReadonly(true)
+	return table, nil
+}
+
+// Sets the functions, constants and sub-tables of the library on table
+func (lib *Library) fill(lua *vm.Lua, table *vm.LuaTable) error {
+	for name, fn := range lib.Functions {
+		funct, err := lib.createFunction(lua, name, fn)
+		if err != nil {
+			return fmt.Errorf("failed to create function %s: %w", lib.qualifiedName(name), err)
+		}
+		if err := table.Set(vm.GoString(name), funct.ToValue()); err != nil {
+			return err
+		}
+	}
+
+	for name, constant := range lib.Constants {
+		value, err := ToValue(lua, constant)
+		if err != nil {
+			return fmt.Errorf("failed to convert constant %s: %w", lib.qualifiedName(name), err)
+		}
+		if err := table.Set(vm.GoString(name), value); err != nil {
+			return err
+		}
+	}
+
+	for name, sub := range lib.Tables {
+		if sub == nil {
+			continue
+		}
+		subTable, err := sub.Build(lua)
+		if err != nil {
+			return err
+		}
+		if err := table.Set(vm.GoString(name), subTable.ToValue()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (lib *Library) createFunction(lua *vm.Lua, name string, fn any) (*vm.LuaFunction, error) {
+	switch f := fn.(type) {
+	case *vm.LuaFunction:
+		// Setting the function takes ownership of it, so a clone is used to keep the
+		// function usable when the library is built again
+		clone, err := lua.CloneValue(f.ToValue())
+		if err != nil {
+			return nil, err
+		}
+		return clone.(*vm.ValueFunction).Value(), nil
+	case vm.FunctionFn:
+		return lua.CreateFunction(f)
+	case func(*vm.CallbackLua, []vm.Value) ([]vm.Value, error):
+		return lua.CreateFunction(f)
+	default:
+		return WrapNamedFunc(lua, name, fn)
+	}
+}
+
+// Returns the name of a member of the library for use in error messages
+func (lib *Library) qualifiedName(name string) string {
+	if lib.Name == "" {
+		return name
+	}
+	return lib.Name + "." + name
+}
+
+// Install builds the library and sets it as a global named lib.Name in globals
+//
+// If the library is lazy, a read-only proxy table is installed instead which builds the library
+// the first time it is indexed.
+func (lib *Library) Install(lua *vm.Lua, globals *vm.LuaTable) error {
+	if lib.Name == "" {
+		return errors.New("cannot install a library without a name")
+	}
+
+	var table *vm.LuaTable
+	var err error
+	if lib.Lazy {
+		table, err = lib.buildProxy(lua)
+	} else {
+		table, err = lib.Build(lua)
+	}
+	if err != nil {
+		return err
+	}
+
+	return globals.Set(vm.GoString(lib.Name), table.ToValue())
+}
+
+// Creates a proxy table that builds the library on first access
+func (lib *Library) buildProxy(lua *vm.Lua) (*vm.LuaTable, error) {
+	var real *vm.LuaTable
+	index := func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		if len(args) < 2 {
+			return nil, errors.New("expected 2 arguments for __index, got " + fmt.Sprint(len(args)))
+		}
+
+		if real == nil {
+			table, err := lib.Build(funcVm.MainState())
+			if err != nil {
+				return nil, err
+			}
+			real = table
+			funcVm.MainState().OnClose(func() {
+				real.Close()
+			})
+		}
+
+		value, err := real.RawGet(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return []vm.Value{value}, nil
+	}
+
+	proxy, err := lua.CreateTable()
+	if err != nil {
+		return nil, err
+	}
+	mt, err := lua.CreateTable()
+	if err != nil {
+		return nil, err
+	}
+	indexFunc, err := lua.CreateFunction(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := mt.Set(vm.GoString("__index"), indexFunc.ToValue()); err != nil {
+		return nil, err
+	}
+	if err := mt.Set(vm.GoString("__metatable"), vm.NewValueBoolean(false)); err != nil {
+		return nil, err
+	}
+	if err := proxy.SetMetatable(mt); err != nil {
+		return nil, err
+	}
+	mt.Close()
+
+	proxy.SetReadonly(true)
+	return proxy, nil
+}
+
+// Returns the library at the given path of sub-tables (including the library itself)
+func (lib *Library) lookup(path []string) *Library {
+	current := lib
+	for _, name := range path {
+		current = current.Tables[name]
+		if current == nil {
+			return nil
+		}
+	}
+	return current
+}
+
+// The prefix of the alias paths used to identify library aliases in JumpToAlias
+const libraryAliasPrefix = "/@library/"
+
+// A LibraryRequirer makes libraries available to require under an alias (e.g. require("@host/db"))
+//
+// All other requires are passed on to the wrapped vm.Require (which may be nil to only allow
+// requiring libraries). The alias is added to the configuration (.luaurc) of the wrapped
+// requirer wherever it does not define the alias itself. Where the wrapped requirer has no
+// configuration, a configuration with only the library aliases is provided at the root if the
+// wrapped requirer implements require.RootReporter (as SimpleRequirer does).
+type LibraryRequirer struct {
+	base      vm.Require
+	libraries map[string]map[string]*Library // alias -> library name -> library
+
+	inLibrary bool     // Whether the current context is inside of a library alias
+	alias     string   // The current library alias
+	path      []string // The current path inside of the library alias
+}
+
+// Creates a new LibraryRequirer wrapping base (which may be nil)
+func NewLibraryRequirer(base vm.Require) *LibraryRequirer {
+	return &LibraryRequirer{
+		base:      base,
+		libraries: make(map[string]map[string]*Library),
+	}
+}
+
+// Makes lib requirable as @alias/<lib.Name>. Sub-tables can be required as @alias/<lib.Name>/<table>.
+func (r *LibraryRequirer) AddLibrary(alias string, lib *Library) error {
+	if alias == "" || strings.ContainsAny(alias, "/@") {
+		return fmt.Errorf("invalid library alias %q", alias)
+	}
+	if lib.Name == "" {
+		return errors.New("cannot add a library without a name")
+	}
+	libs, ok := r.libraries[alias]
+	if !ok {
+		libs = make(map[string]*Library)
+		r.libraries[alias] = libs
+	}
+	libs[lib.Name] = lib
+	return nil
+}
+
+// Returns the library the current context points at, if any
+func (r *LibraryRequirer) current() *Library {
+	if !r.inLibrary || len(r.path) == 0 {
+		return nil
+	}
+	lib := r.libraries[r.alias][r.path[0]]
+	if lib == nil {
+		return nil
+	}
+	return lib.lookup(r.path[1:])
+}
+
+func (r *LibraryRequirer) IsRequireAllowed(chunkName string) bool {
+	if r.base == nil {
+		return true
+	}
+	return r.base.IsRequireAllowed(chunkName)
+}
+
+func (r *LibraryRequirer) Reset(chunkName string) *vm.NavigationResult {
+	r.inLibrary = false
+	if r.base == nil {
+		return nil
+	}
+	return r.base.Reset(chunkName)
+}
+
+func (r *LibraryRequirer) JumpToAlias(path string) *vm.NavigationResult {
+	if alias, ok := strings.CutPrefix(path, libraryAliasPrefix); ok {
+		if _, ok := r.libraries[alias]; ok {
+			r.inLibrary = true
+			r.alias = alias
+			r.path = nil
+			return nil
+		}
+	}
+
+	r.inLibrary = false
+	if r.base == nil {
+		return vm.NotFoundNavigationResult()
+	}
+	return r.base.JumpToAlias(path)
+}
+
+func (r *LibraryRequirer) ToParent() *vm.NavigationResult {
+	if r.inLibrary {
+		if len(r.path) == 0 {
+			return vm.NotFoundNavigationResult()
+		}
+		r.path = r.path[:len(r.path)-1]
+		return nil
+	}
+	if r.base == nil {
+		return vm.NotFoundNavigationResult()
+	}
+	return r.base.ToParent()
+}
+
+func (r *LibraryRequirer) ToChild(name string) *vm.NavigationResult {
+	if r.inLibrary {
+		r.path = append(r.path, name)
+		if r.current() == nil {
+			r.path = r.path[:len(r.path)-1]
+			return vm.NotFoundNavigationResult()
+		}
+		return nil
+	}
+	if r.base == nil {
+		return vm.NotFoundNavigationResult()
+	}
+	return r.base.ToChild(name)
+}
+
+func (r *LibraryRequirer) HasModule() bool {
+	if r.inLibrary {
+		return r.current() != nil
+	}
+	if r.base == nil {
+		return false
+	}
+	return r.base.HasModule()
+}
+
+func (r *LibraryRequirer) CacheKey() string {
+	if r.inLibrary || r.base == nil {
+		return r.ModuleKey()
+	}
+	return r.base.CacheKey()
+}
+
+// ModuleKey returns the cache key of the current module without side effects (see require.ModuleKeyer)
+//
+// An empty string is returned outside of libraries if the wrapped requirer does not implement
+// require.ModuleKeyer.
+func (r *LibraryRequirer) ModuleKey() string {
+	if r.inLibrary {
+		return "@library:" + r.alias + "/" + strings.Join(r.path, "/")
+	}
+	if keyer, ok := r.base.(require.ModuleKeyer); ok {
+		return keyer.ModuleKey()
+	}
+	return ""
+}
+
+func (r *LibraryRequirer) HasConfig() bool {
+	if r.inLibrary {
+		return false
+	}
+	if r.base == nil {
+		return true
+	}
+	if r.base.HasConfig() {
+		return true
+	}
+	// Without a configuration of the wrapped requirer, the library aliases are only provided at the
+	// root, as Luau stops searching upwards at the first configuration (which would hide the aliases
+	// of configuration files in parent directories)
+	reporter, ok := r.base.(require.RootReporter)
+	return ok && reporter.IsAtRoot()
+}
+
+func (r *LibraryRequirer) Config() ([]byte, error) {
+	var config []byte
+	if r.base != nil && r.base.HasConfig() {
+		var err error
+		config, err = r.base.Config()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	aliases := make(map[string]string, len(r.libraries))
+	for alias := range r.libraries {
+		aliases[alias] = libraryAliasPrefix + alias
+	}
+	injected, err := require.InjectAliases(config, aliases, false)
+	if err != nil {
+		return config, nil // Invalid configurations are left as-is so Luau reports the error
+	}
+	return injected, nil
+}
+
+func (r *LibraryRequirer) Loader(cb *vm.CallbackLua) (*vm.LuaFunction, error) {
+	if !r.inLibrary {
+		if r.base == nil {
+			return nil, errors.New("no module found")
+		}
+		return r.base.Loader(cb)
+	}
+
+	lib := r.current()
+	if lib == nil {
+		return nil, errors.New("no library found at @" + r.alias + "/" + strings.Join(r.path, "/"))
+	}
+	return cb.MainState().CreateFunction(func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		table, err := lib.Build(funcVm.MainState())
+		if err != nil {
+			return nil, err
+		}
+		return []vm.Value{table.ToValue()}, nil
+	})
+}
@@ -0,0 +1,273 @@
+package vmutils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/koeng101/gluau/vm"
+)
+
+// An operand of a binary operator
+type Operand[T any] struct {
+	Self  *T       // Set if the operand is user data of type T
+	Value vm.Value // The operand itself
+}
+
+// Returns the operand as a number if it is one
+func (o Operand[T]) Number() (float64, bool) {
+	switch v := o.Value.(type) {
+	case *vm.ValueInteger:
+		return float64(v.Value()), true
+	case *vm.ValueNumber:
+		return v.Value(), true
+	}
+	return 0, false
+}
+
+// The handlers of a binary operator metamethod
+type operator[T any] struct {
+	binary func(a, b *T) (*T, error)
+	number func(self *T, n float64, swapped bool) (*T, error)
+	other  func(funcVm *vm.CallbackLua, a, b Operand[T]) ([]vm.Value, error)
+}
+
+// Metamethods of arithmetic operators along with the name Luau uses for them in errors
+var arithmeticOps = map[string]string{
+	"__add":  "add",
+	"__sub":  "sub",
+	"__mul":  "mul",
+	"__div":  "div",
+	"__idiv": "idiv",
+	"__mod":  "mod",
+	"__pow":  "pow",
+}
+
+// Returns the operator of a metamethod, creating it if needed
+func (tud *TypedUserData[T]) operator(op string) *operator[T] {
+	o, ok := tud.operators[op]
+	if !ok {
+		o = &operator[T]{}
+		tud.operators[op] = o
+	}
+	return o
+}
+
+// Adds an arithmetic operator (__add, __sub, __mul, __div, __idiv, __mod or __pow) between two
+// values of the user data, returning a new value of the user data
+//
+// Children inherit operators (see SetParent), which still produce values of the parent type.
+func (tud *TypedUserData[T]) AddBinaryOp(op string, fn func(a, b *T) (*T, error)) error {
+	if _, ok := arithmeticOps[op]; !ok {
+		return fmt.Errorf("%s is not an arithmetic metamethod", op)
+	}
+	tud.operator(op).binary = fn
+	return nil
+}
+
+// Adds an arithmetic operator (__add, __sub, __mul, __div, __idiv, __mod or __pow) between a value
+// of the user data and a number, returning a new value of the user data
+//
+// The operator is called for both `ud op n` and `n op ud`, swapped is true in the latter case.
+func (tud *TypedUserData[T]) AddNumberOp(op string, fn func(self *T, n float64, swapped bool) (*T, error)) error {
+	if _, ok := arithmeticOps[op]; !ok {
+		return fmt.Errorf("%s is not an arithmetic metamethod", op)
+	}
+	tud.operator(op).number = fn
+	return nil
+}
+
+// Returns whether a metamethod is a binary operator, which is always called with two operands
+func isBinaryOperator(op string) bool {
+	if _, ok := arithmeticOps[op]; ok {
+		return true
+	}
+	switch op {
+	case "__concat", "__eq", "__lt", "__le":
+		return true
+	}
+	return false
+}
+
+// Adds a binary operator metamethod (an arithmetic metamethod, __concat, __eq, __lt or __le)
+// which is called when none of the typed handlers added using AddBinaryOp or AddNumberOp match
+// the operands
+//
+// At least one of a and b has Self set. Other metamethods (e.g. __call, which takes any number
+// of arguments) must be added using AddMetamethod.
+func (tud *TypedUserData[T]) AddOperator(op string, fn func(funcVm *vm.CallbackLua, a, b Operand[T]) ([]vm.Value, error)) error {
+	if !isBinaryOperator(op) {
+		return fmt.Errorf("%s is not a binary operator metamethod (use AddMetamethod instead)", op)
+	}
+	tud.operator(op).other = fn
+	return nil
+}
+
+// Adds a comparison metamethod (__eq, __lt or __le) between two values of the user data
+//
+// __eq is false when the other value is not user data of type T, while __lt and __le
+// raise a Luau error.
+func (tud *TypedUserData[T]) AddComparison(op string, fn func(a, b *T) (bool, error)) error {
+	if op != "__eq" && op != "__lt" && op != "__le" {
+		return fmt.Errorf("%s is not a comparison metamethod", op)
+	}
+	tud.operator(op).other = func(_ *vm.CallbackLua, a, b Operand[T]) ([]vm.Value, error) {
+		if a.Self == nil || b.Self == nil {
+			if op == "__eq" {
+				return []vm.Value{vm.NewValueBoolean(false)}, nil
+			}
+			return nil, errors.New("attempt to compare " + luauTypeName(a.Value) + " and " + luauTypeName(b.Value))
+		}
+		result, err := fn(a.Self, b.Self)
+		if err != nil {
+			return nil, err
+		}
+		return []vm.Value{vm.NewValueBoolean(result)}, nil
+	}
+	return nil
+}
+
+// Adds a unary operator metamethod (__unm) returning a new value of the user data
+func (tud *TypedUserData[T]) AddUnaryOp(op string, fn func(self *T) (*T, error)) error {
+	if op != "__unm" {
+		return fmt.Errorf("%s is not a unary operator metamethod", op)
+	}
+	tud.metamethods[op] = func(self *T, funcVm *vm.CallbackLua, _ []vm.Value) ([]vm.Value, error) {
+		result, err := fn(self)
+		if err != nil {
+			return nil, err
+		}
+		return tud.createResult(funcVm, result)
+	}
+	return nil
+}
+
+// Sets the __len metamethod of the user data
+func (tud *TypedUserData[T]) SetLen(fn func(self *T) (int, error)) {
+	tud.metamethods["__len"] = func(self *T, _ *vm.CallbackLua, _ []vm.Value) ([]vm.Value, error) {
+		n, err := fn(self)
+		if err != nil {
+			return nil, err
+		}
+		return []vm.Value{vm.NewValueInteger(int64(n))}, nil
+	}
+}
+
+// Sets the __tostring metamethod of the user data
+//
+// If no __tostring metamethod is set and T (or *T) implements fmt.Stringer, String is used.
+func (tud *TypedUserData[T]) SetToString(fn func(self *T) (string, error)) {
+	tud.metamethods["__tostring"] = func(self *T, _ *vm.CallbackLua, _ []vm.Value) ([]vm.Value, error) {
+		s, err := fn(self)
+		if err != nil {
+			return nil, err
+		}
+		return []vm.Value{vm.GoString(s)}, nil
+	}
+}
+
+// Returns a __tostring metamethod using fmt.Stringer if T or *T implement it
+func stringerToString[T any]() func(*T, *vm.CallbackLua, []vm.Value) ([]vm.Value, error) {
+	stringer := reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	ptrType := reflect.TypeOf((*T)(nil))
+	if !ptrType.Implements(stringer) && !ptrType.Elem().Implements(stringer) {
+		return nil
+	}
+
+	return func(self *T, _ *vm.CallbackLua, _ []vm.Value) ([]vm.Value, error) {
+		var s fmt.Stringer
+		if ptrStringer, ok := any(self).(fmt.Stringer); ok {
+			s = ptrStringer
+		} else {
+			s = any(*self).(fmt.Stringer)
+		}
+		return []vm.Value{vm.GoString(s.String())}, nil
+	}
+}
+
+// Creates user data holding the result of an operator
+func (tud *TypedUserData[T]) createResult(funcVm *vm.CallbackLua, result *T) ([]vm.Value, error) {
+	if result == nil {
+		return []vm.Value{vm.NewValueNil()}, nil
+	}
+	// Operators are set up on flattened copies, so use the original TypedUserData for its registered metatables
+	owner := tud
+	if tud.origin != nil {
+		owner = tud.origin
+	}
+	ud, err := owner.Create(funcVm.MainState(), result)
+	if err != nil {
+		return nil, err
+	}
+	return []vm.Value{ud.ToValue()}, nil
+}
+
+// Sets the operator metamethods on the metatable of the user data
+func (tud *TypedUserData[T]) setOperators(lua *vm.Lua, udMt *vm.LuaTable) error {
+	for op, o := range tud.operators {
+		op, o := op, o // Capture the loop variables
+		callback := func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+			if len(args) < 2 {
+				return nil, errors.New("expected 2 arguments for " + op + ", got " + fmt.Sprint(len(args)))
+			}
+			a, b := operandOf[T](args[0]), operandOf[T](args[1])
+			return tud.callOperator(funcVm, op, o, a, b)
+		}
+		funct, err := lua.CreateFunction(callback)
+		if err != nil {
+			return err
+		}
+		if err := udMt.Set(vm.GoString(op), funct.ToValue()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dispatches a binary operator to the handler matching its operands
+func (tud *TypedUserData[T]) callOperator(funcVm *vm.CallbackLua, op string, o *operator[T], a, b Operand[T]) ([]vm.Value, error) {
+	if o.binary != nil && a.Self != nil && b.Self != nil {
+		result, err := o.binary(a.Self, b.Self)
+		if err != nil {
+			return nil, err
+		}
+		return tud.createResult(funcVm, result)
+	}
+
+	if o.number != nil {
+		if n, ok := b.Number(); ok && a.Self != nil {
+			result, err := o.number(a.Self, n, false)
+			if err != nil {
+				return nil, err
+			}
+			return tud.createResult(funcVm, result)
+		}
+		if n, ok := a.Number(); ok && b.Self != nil {
+			result, err := o.number(b.Self, n, true)
+			if err != nil {
+				return nil, err
+			}
+			return tud.createResult(funcVm, result)
+		}
+	}
+
+	if o.other != nil {
+		return o.other(funcVm, a, b)
+	}
+
+	if name, ok := arithmeticOps[op]; ok {
+		return nil, errors.New("attempt to perform arithmetic (" + name + ") on " + luauTypeName(a.Value) + " and " + luauTypeName(b.Value))
+	}
+	return nil, errors.New("no " + op + " handler for " + luauTypeName(a.Value) + " and " + luauTypeName(b.Value))
+}
+
+// Returns a value as an operand
+func operandOf[T any](v vm.Value) Operand[T] {
+	operand := Operand[T]{Value: v}
+	if _, ok := v.(*vm.ValueUserData); ok {
+		if self, _, err := ParseSelf[T]("", []vm.Value{v}); err == nil {
+			operand.Self = self
+		}
+	}
+	return operand
+}
@@ -0,0 +1,125 @@
+package require
+
+import "encoding/json"
+
+// InjectAliases adds aliases to the contents of a configuration file (.luaurc)
+//
+// Aliases already defined by the configuration are only replaced if overwrite is set. The
+// configuration may contain comments and trailing commas (which are removed). An empty
+// configuration is treated as an empty object.
+func InjectAliases(config []byte, aliases map[string]string, overwrite bool) ([]byte, error) {
+	parsed := map[string]any{}
+	if stripped := stripJSONC(config); len(trimSpace(stripped)) != 0 {
+		if err := json.Unmarshal(stripped, &parsed); err != nil {
+			return nil, err
+		}
+	}
+
+	existing, ok := parsed["aliases"].(map[string]any)
+	if !ok {
+		existing = make(map[string]any)
+		parsed["aliases"] = existing
+	}
+	for alias, path := range aliases {
+		if _, ok := existing[alias]; ok && !overwrite {
+			continue
+		}
+		existing[alias] = path
+	}
+
+	return json.Marshal(parsed)
+}
+
+// Returns the names of the aliases defined by a configuration file (.luaurc)
+func configAliases(config []byte) ([]string, error) {
+	var parsed struct {
+		Aliases map[string]any `json:"aliases"`
+	}
+	if stripped := stripJSONC(config); len(trimSpace(stripped)) != 0 {
+		if err := json.Unmarshal(stripped, &parsed); err != nil {
+			return nil, err
+		}
+	}
+	aliases := make([]string, 0, len(parsed.Aliases))
+	for alias := range parsed.Aliases {
+		aliases = append(aliases, alias)
+	}
+	return aliases, nil
+}
+
+// Returns src without leading and trailing JSON whitespace
+func trimSpace(src []byte) []byte {
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
+	for len(src) > 0 && isSpace(src[0]) {
+		src = src[1:]
+	}
+	for len(src) > 0 && isSpace(src[len(src)-1]) {
+		src = src[:len(src)-1]
+	}
+	return src
+}
+
+// Converts JSONC (JSON with comments and trailing commas, as used by .luaurc files) to JSON
+//
+// Comments and trailing commas are replaced with whitespace, so offsets into the result are
+// offsets into src.
+func stripJSONC(src []byte) []byte {
+	out := make([]byte, 0, len(src))
+	pendingComma := -1 // Index in out of a comma which may be a trailing comma
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '"':
+			// Copy the string as-is
+			start := i
+			for i++; i < len(src) && src[i] != '"'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+			end := i + 1
+			if end > len(src) {
+				end = len(src)
+			}
+			out = append(out, src[start:end]...)
+			pendingComma = -1
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for ; i < len(src) && src[i] != '\n'; i++ {
+				out = append(out, ' ')
+			}
+			i-- // Keep the newline
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			start := i
+			i += 2
+			for i+1 < len(src) && !(src[i] == '*' && src[i+1] == '/') {
+				i++
+			}
+			i++
+			if i >= len(src) {
+				i = len(src) - 1
+			}
+			for _, commented := range src[start : i+1] {
+				if commented == '\n' {
+					out = append(out, '\n')
+				} else {
+					out = append(out, ' ')
+				}
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			out = append(out, c)
+		case c == '}' || c == ']':
+			if pendingComma >= 0 {
+				out[pendingComma] = ' '
+			}
+			out = append(out, c)
+			pendingComma = -1
+		case c == ',':
+			out = append(out, c)
+			pendingComma = len(out) - 1
+		default:
+			out = append(out, c)
+			pendingComma = -1
+		}
+	}
+	return out
+}
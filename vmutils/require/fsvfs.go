@@ -0,0 +1,118 @@
+package require
+
+import (
+	"archive/zip"
+	"embed"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// An FSVfs is a Vfs backed by any fs.FS (e.g. an embed.FS or a zip archive)
+//
+// Paths are resolved relative to the root of the file system: the absolute path "/foo/bar.luau"
+// and the relative path "./foo/bar.luau" both refer to "foo/bar.luau" in the fs.FS. Paths
+// that would escape the root of the file system are not found.
+type FSVfs struct {
+	fs fs.FS
+}
+
+// NewFSVfs creates a Vfs from an fs.FS
+func NewFSVfs(fsys fs.FS) *FSVfs {
+	return &FSVfs{fs: fsys}
+}
+
+// NewEmbedVfs creates a Vfs from an embed.FS, using the directory root of the embedded files as
+// the root of the Vfs (as the paths of embedded files include the directory they are in)
+func NewEmbedVfs(efs embed.FS, root string) (*FSVfs, error) {
+	if root == "" || root == "." {
+		return NewFSVfs(efs), nil
+	}
+	sub, err := fs.Sub(efs, root)
+	if err != nil {
+		return nil, err
+	}
+	return NewFSVfs(sub), nil
+}
+
+// NewZipVfs creates a Vfs from a zip archive
+//
+// Directories do not need to be stored in the archive, they are inferred from the paths of the files.
+func NewZipVfs(r io.ReaderAt, size int64) (*FSVfs, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return NewFSVfs(zr), nil
+}
+
+// OpenZipVfs opens a zip archive on disk as a Vfs. The Vfs must be closed using Close when no longer used.
+func OpenZipVfs(name string) (*FSVfs, error) {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewFSVfs(zr), nil
+}
+
+// Returns the fs.FS path of a Vfs path
+func (v *FSVfs) fsPath(name string) string {
+	name = strings.TrimLeft(name, "/")
+	name = path.Clean(name)
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+func (v *FSVfs) Open(name string) (fs.File, error) {
+	return v.fs.Open(v.fsPath(name))
+}
+
+func (v *FSVfs) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(v.fs, v.fsPath(name))
+}
+
+// Cwd returns "/" as relative paths are resolved relative to the root of the file system
+func (v *FSVfs) Cwd() string {
+	return "/"
+}
+
+func (v *FSVfs) Join(paths ...string) string {
+	return unixJoin(paths...)
+}
+
+func (v *FSVfs) NormalizePath(path string) string {
+	return unixnormalizePath(path)
+}
+
+func (v *FSVfs) IsAbsolutePath(path string) bool {
+	return unixisAbsolutePath(path)
+}
+
+// Close closes the underlying file system if it implements io.Closer (e.g. when created using OpenZipVfs)
+func (v *FSVfs) Close() error {
+	if closer, ok := v.fs.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Joins paths using "/" without duplicating separators between them, skipping empty paths
+func unixJoin(paths ...string) string {
+	var b strings.Builder
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			if !strings.HasSuffix(b.String(), "/") {
+				b.WriteByte('/')
+			}
+			p = strings.TrimLeft(p, "/")
+		}
+		b.WriteString(p)
+	}
+	return b.String()
+}
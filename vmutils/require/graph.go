@@ -0,0 +1,355 @@
+package require
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/koeng101/gluau/vm"
+)
+
+// An edge of a DependencyGraph, From required To
+type DependencyEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// A DependencyGraph records which modules required which modules
+//
+// Nodes are the cache keys of modules, except for requirers which are not modules themselves
+// (e.g. the REPL) which are identified by their chunk name. A DependencyGraph is safe for
+// concurrent use.
+type DependencyGraph struct {
+	mu    sync.Mutex
+	nodes map[string]bool
+	edges map[string]map[string]bool // requirer -> required modules
+}
+
+// Creates a new, empty DependencyGraph
+func NewDependencyGraph() *DependencyGraph {
+	return &DependencyGraph{
+		nodes: make(map[string]bool),
+		edges: make(map[string]map[string]bool),
+	}
+}
+
+// AddNode adds a module to the graph
+func (g *DependencyGraph) AddNode(node string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.nodes[node] = true
+}
+
+// AddEdge records that from required to
+func (g *DependencyGraph) AddEdge(from, to string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.nodes[from] = true
+	g.nodes[to] = true
+	if g.edges[from] == nil {
+		g.edges[from] = make(map[string]bool)
+	}
+	g.edges[from][to] = true
+}
+
+// Nodes returns the sorted modules of the graph
+func (g *DependencyGraph) Nodes() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return sortedKeys(g.nodes)
+}
+
+// Edges returns the edges of the graph, sorted by requirer and then by required module
+func (g *DependencyGraph) Edges() []DependencyEdge {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var edges []DependencyEdge
+	for _, from := range sortedKeys(g.edges) {
+		for _, to := range sortedKeys(g.edges[from]) {
+			edges = append(edges, DependencyEdge{From: from, To: to})
+		}
+	}
+	return edges
+}
+
+// Dependencies returns the sorted modules required by node
+func (g *DependencyGraph) Dependencies(node string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return sortedKeys(g.edges[node])
+}
+
+// Dependents returns the sorted modules which required node
+func (g *DependencyGraph) Dependents(node string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var out []string
+	for from, tos := range g.edges {
+		if tos[node] {
+			out = append(out, from)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// TransitiveDependents returns the given modules along with all modules that (transitively)
+// required them, sorted
+func (g *DependencyGraph) TransitiveDependents(nodes ...string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	dependents := make(map[string][]string)
+	for from, tos := range g.edges {
+		for to := range tos {
+			dependents[to] = append(dependents[to], from)
+		}
+	}
+
+	seen := make(map[string]bool)
+	queue := append([]string(nil), nodes...)
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if seen[node] {
+			continue
+		}
+		seen[node] = true
+		queue = append(queue, dependents[node]...)
+	}
+	return sortedKeys(seen)
+}
+
+// Removes the edges from node, e.g. because it is required (and so records its dependencies) again
+func (g *DependencyGraph) removeDependencies(node string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.edges, node)
+}
+
+// A CycleError is returned by TopologicalOrder when the graph contains a cycle
+type CycleError struct {
+	// The modules of the cycle, starting and ending with the same module
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return "dependency cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// FindCycle returns a cycle of the graph (starting and ending with the same module),
+// or nil if the graph is acyclic
+func (g *DependencyGraph) FindCycle() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.findCycle()
+}
+
+func (g *DependencyGraph) findCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g.nodes))
+	var stack []string
+
+	var visit func(node string) []string
+	visit = func(node string) []string {
+		state[node] = visiting
+		stack = append(stack, node)
+		for _, next := range sortedKeys(g.edges[node]) {
+			switch state[next] {
+			case visiting:
+				// The cycle is the part of the stack starting at next
+				for i, n := range stack {
+					if n == next {
+						return append(append([]string(nil), stack[i:]...), next)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[node] = visited
+		return nil
+	}
+
+	for _, node := range sortedKeys(g.nodes) {
+		if state[node] == unvisited {
+			if cycle := visit(node); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// TopologicalOrder returns the modules of the graph ordered such that every module comes after
+// the modules it required, or a *CycleError if the graph contains a cycle
+//
+// Modules without an order between them are sorted by name.
+func (g *DependencyGraph) TopologicalOrder() ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if cycle := g.findCycle(); cycle != nil {
+		return nil, &CycleError{Path: cycle}
+	}
+
+	remaining := make(map[string]int, len(g.nodes)) // node -> number of dependencies not yet ordered
+	dependents := make(map[string][]string)
+	for node := range g.nodes {
+		remaining[node] = len(g.edges[node])
+	}
+	for from, tos := range g.edges {
+		for to := range tos {
+			dependents[to] = append(dependents[to], from)
+		}
+	}
+
+	var ready []string
+	for node, n := range remaining {
+		if n == 0 {
+			ready = append(ready, node)
+		}
+	}
+
+	order := make([]string, 0, len(g.nodes))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		node := ready[0]
+		ready = ready[1:]
+		order = append(order, node)
+		for _, dependent := range dependents[node] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	return order, nil
+}
+
+// DOT returns the graph in the Graphviz DOT format
+func (g *DependencyGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	for _, node := range g.Nodes() {
+		fmt.Fprintf(&b, "\t%s;\n", strconv.Quote(node))
+	}
+	for _, edge := range g.Edges() {
+		fmt.Fprintf(&b, "\t%s -> %s;\n", strconv.Quote(edge.From), strconv.Quote(edge.To))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// MarshalJSON encodes the graph as {"nodes": [...], "edges": [{"from": ..., "to": ...}, ...]}
+func (g *DependencyGraph) MarshalJSON() ([]byte, error) {
+	edges := g.Edges()
+	if edges == nil {
+		edges = []DependencyEdge{}
+	}
+	return json.Marshal(struct {
+		Nodes []string         `json:"nodes"`
+		Edges []DependencyEdge `json:"edges"`
+	}{
+		Nodes: g.Nodes(),
+		Edges: edges,
+	})
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for key := range m {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// A ModuleKeyer is implemented by vm.Require implementations which can return the cache key of
+// the current module without side effects (CacheKey may record that the module was required)
+//
+// An empty string means the cache key is not known.
+type ModuleKeyer interface {
+	ModuleKey() string
+}
+
+// A RootReporter is implemented by vm.Require implementations which can report whether the
+// current module is at the root of their file system, where wrapping requirers may provide a
+// configuration of their own without hiding the configurations of parent directories
+type RootReporter interface {
+	IsAtRoot() bool
+}
+
+// A DependencyRecorder wraps a vm.Require, recording the modules required through it
+// into a DependencyGraph
+//
+// An edge from the requirer to the required module is added whenever navigation to a module
+// succeeds (including when the module is already cached). Modules requiring themselves are
+// recorded as cycles.
+//
+// If the wrapped vm.Require implements ModuleKeyer, requirers which are modules are identified by
+// their cache key so edges connect. Otherwise (and for requirers which are not modules) they are
+// identified by their chunk name.
+type DependencyRecorder struct {
+	vm.Require
+	graph    *DependencyGraph
+	requirer string // The node of the module currently requiring a module
+}
+
+// Wraps base, recording the modules required through it into graph
+func NewDependencyRecorder(base vm.Require, graph *DependencyGraph) *DependencyRecorder {
+	return &DependencyRecorder{
+		Require: base,
+		graph:   graph,
+	}
+}
+
+// Graph returns the graph the recorder records into
+func (r *DependencyRecorder) Graph() *DependencyGraph {
+	return r.graph
+}
+
+func (r *DependencyRecorder) Reset(chunkName string) *vm.NavigationResult {
+	result := r.Require.Reset(chunkName)
+	r.requirer = chunkName
+	if keyer, ok := r.Require.(ModuleKeyer); ok && result == nil && r.Require.HasModule() {
+		if key := keyer.ModuleKey(); key != "" {
+			r.requirer = key
+		}
+	}
+	r.graph.AddNode(r.requirer)
+	return result
+}
+
+// ModuleKey returns the cache key of the current module if the wrapped vm.Require implements
+// ModuleKeyer (see ModuleKeyer)
+func (r *DependencyRecorder) ModuleKey() string {
+	if keyer, ok := r.Require.(ModuleKeyer); ok {
+		return keyer.ModuleKey()
+	}
+	return ""
+}
+
+// IsAtRoot reports whether the wrapped vm.Require is at its root (see RootReporter)
+func (r *DependencyRecorder) IsAtRoot() bool {
+	if reporter, ok := r.Require.(RootReporter); ok {
+		return reporter.IsAtRoot()
+	}
+	return false
+}
+
+func (r *DependencyRecorder) CacheKey() string {
+	key := r.Require.CacheKey()
+	r.graph.AddEdge(r.requirer, key)
+	return key
+}
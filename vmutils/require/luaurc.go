@@ -0,0 +1,366 @@
+package require
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// A parsed configuration file (.luaurc)
+type LuauRC struct {
+	// The path of the configuration file, used in errors
+	Path string
+
+	// The type checking mode ("nocheck", "nonstrict" or "strict"), empty if not set
+	LanguageMode string
+
+	// Lints to enable or disable by name, "*" applies to all lints
+	Lint map[string]bool
+
+	// Whether lints and type errors are reported as errors, nil if not set
+	LintErrors *bool
+	TypeErrors *bool
+
+	// Names of additional globals
+	Globals []string
+
+	// Aliases usable in requires (e.g. require("@lib/x")), keyed by their name as written
+	Aliases map[string]string
+}
+
+// A LuauRCError is an error in a configuration file at a given position
+type LuauRCError struct {
+	Path    string
+	Line    int // 1-based
+	Column  int // 1-based, in bytes
+	Message string
+}
+
+func (e *LuauRCError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Message)
+}
+
+// LuauRCErrors holds all validation errors of a configuration file
+type LuauRCErrors []*LuauRCError
+
+func (e LuauRCErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// The valid values of languageMode
+var luauLanguageModes = map[string]bool{"nocheck": true, "nonstrict": true, "strict": true}
+
+// ParseLuauRC parses and validates a configuration file (.luaurc) located at path
+//
+// Like Luau, comments and trailing commas are accepted and an empty file is an empty
+// configuration. Syntax errors are returned as a *LuauRCError, while validation errors
+// (unknown keys, values of the wrong type, invalid alias names) are all returned together
+// as LuauRCErrors.
+func ParseLuauRC(path string, data []byte) (*LuauRC, error) {
+	p := &luauRCParser{path: path, src: data, stripped: stripJSONC(data)}
+	return p.parse()
+}
+
+// Alias returns the path of an alias, compared case-insensitively like Luau does
+func (c *LuauRC) Alias(name string) (string, bool) {
+	name = strings.TrimPrefix(name, "@")
+	for alias, path := range c.Aliases {
+		if strings.EqualFold(alias, name) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+type luauRCParser struct {
+	path     string
+	src      []byte
+	stripped []byte
+	dec      *json.Decoder
+	errs     LuauRCErrors
+}
+
+// Returns an error at the given offset into the file
+func (p *luauRCParser) errorAt(offset int64, format string, args ...any) *LuauRCError {
+	if offset > int64(len(p.src)) {
+		offset = int64(len(p.src))
+	}
+	line, column := 1, 1
+	for _, c := range p.src[:offset] {
+		if c == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return &LuauRCError{Path: p.path, Line: line, Column: column, Message: fmt.Sprintf(format, args...)}
+}
+
+// Converts an error of the decoder into a *LuauRCError
+func (p *luauRCParser) syntaxError(err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset := syntaxErr.Offset
+		if offset > 0 {
+			offset-- // The offset is after the offending byte
+		}
+		return p.errorAt(offset, "%s", syntaxErr.Error())
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return p.errorAt(int64(len(p.src)), "unexpected end of file")
+	}
+	return p.errorAt(p.dec.InputOffset(), "%s", err.Error())
+}
+
+// Reads the next key of an object, returning it and the offset it starts at
+func (p *luauRCParser) key() (string, int64, error) {
+	tok, err := p.dec.Token()
+	if err != nil {
+		return "", 0, p.syntaxError(err)
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", 0, p.errorAt(p.dec.InputOffset(), "expected an object key")
+	}
+	end := p.dec.InputOffset()
+	start := int64(bytes.LastIndexByte(p.stripped[:end-1], '"'))
+	return key, start, nil
+}
+
+// Reads the next value, returning it and the offset it starts at
+func (p *luauRCParser) value() (json.RawMessage, int64, error) {
+	var raw json.RawMessage
+	if err := p.dec.Decode(&raw); err != nil {
+		return nil, 0, p.syntaxError(err)
+	}
+	return raw, p.dec.InputOffset() - int64(len(raw)), nil
+}
+
+// Expects the next token to be the start of an object
+func (p *luauRCParser) objectStart(name string) (bool, error) {
+	offset := p.dec.InputOffset()
+	tok, err := p.dec.Token()
+	if err != nil {
+		return false, p.syntaxError(err)
+	}
+	if delim, ok := tok.(json.Delim); ok && delim == '{' {
+		return true, nil
+	}
+	if delim, ok := tok.(json.Delim); ok && delim == '[' {
+		// Skip the array so parsing can continue
+		for p.dec.More() {
+			if _, _, err := p.value(); err != nil {
+				return false, err
+			}
+		}
+		if _, err := p.dec.Token(); err != nil {
+			return false, p.syntaxError(err)
+		}
+	}
+	p.errs = append(p.errs, p.errorAt(p.nextNonSpace(offset), "%s must be an object", name))
+	return false, nil
+}
+
+// Returns the offset of the first non-whitespace byte at or after offset
+func (p *luauRCParser) nextNonSpace(offset int64) int64 {
+	for offset < int64(len(p.stripped)) {
+		switch p.stripped[offset] {
+		case ' ', '\t', '\n', '\r', ':', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+func (p *luauRCParser) parse() (*LuauRC, error) {
+	rc := &LuauRC{Path: p.path}
+	if len(trimSpace(p.stripped)) == 0 {
+		return rc, nil
+	}
+
+	p.dec = json.NewDecoder(bytes.NewReader(p.stripped))
+	ok, err := p.objectStart("the configuration")
+	if err != nil || !ok {
+		if err == nil {
+			err = p.errs
+		}
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for p.dec.More() {
+		key, keyOffset, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if seen[key] {
+			p.errs = append(p.errs, p.errorAt(keyOffset, "duplicate key %q", key))
+		}
+		seen[key] = true
+
+		switch key {
+		case "aliases":
+			aliases, err := p.parseAliases()
+			if err != nil {
+				return nil, err
+			}
+			rc.Aliases = aliases
+		case "lint":
+			lint, err := p.parseLint()
+			if err != nil {
+				return nil, err
+			}
+			rc.Lint = lint
+		default:
+			raw, offset, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			p.setField(rc, key, keyOffset, raw, offset)
+		}
+	}
+	if _, err := p.dec.Token(); err != nil {
+		return nil, p.syntaxError(err)
+	}
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, p.errorAt(p.nextNonSpace(p.dec.InputOffset()), "unexpected data after the configuration")
+	}
+
+	if len(p.errs) > 0 {
+		return nil, p.errs
+	}
+	return rc, nil
+}
+
+// Sets a field with a simple value
+func (p *luauRCParser) setField(rc *LuauRC, key string, keyOffset int64, raw json.RawMessage, offset int64) {
+	switch key {
+	case "languageMode":
+		if err := json.Unmarshal(raw, &rc.LanguageMode); err != nil || !luauLanguageModes[rc.LanguageMode] {
+			rc.LanguageMode = ""
+			p.errs = append(p.errs, p.errorAt(offset, `languageMode must be one of "nocheck", "nonstrict" or "strict"`))
+		}
+	case "lintErrors", "typeErrors":
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			p.errs = append(p.errs, p.errorAt(offset, "%s must be a boolean", key))
+			return
+		}
+		if key == "lintErrors" {
+			rc.LintErrors = &b
+		} else {
+			rc.TypeErrors = &b
+		}
+	case "globals":
+		if err := json.Unmarshal(raw, &rc.Globals); err != nil {
+			rc.Globals = nil
+			p.errs = append(p.errs, p.errorAt(offset, "globals must be an array of strings"))
+		}
+	default:
+		p.errs = append(p.errs, p.errorAt(keyOffset, "unknown key %q", key))
+	}
+}
+
+func (p *luauRCParser) parseLint() (map[string]bool, error) {
+	ok, err := p.objectStart("lint")
+	if err != nil || !ok {
+		return nil, err
+	}
+	lint := make(map[string]bool)
+	for p.dec.More() {
+		name, _, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		raw, offset, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		var enabled bool
+		if err := json.Unmarshal(raw, &enabled); err != nil {
+			p.errs = append(p.errs, p.errorAt(offset, "lint %q must be a boolean", name))
+			continue
+		}
+		lint[name] = enabled
+	}
+	if _, err := p.dec.Token(); err != nil {
+		return nil, p.syntaxError(err)
+	}
+	return lint, nil
+}
+
+func (p *luauRCParser) parseAliases() (map[string]string, error) {
+	ok, err := p.objectStart("aliases")
+	if err != nil || !ok {
+		return nil, err
+	}
+	aliases := make(map[string]string)
+	seen := make(map[string]string) // lowercased alias -> alias
+	for p.dec.More() {
+		alias, keyOffset, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		raw, offset, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		if msg := validateAliasName(alias); msg != "" {
+			p.errs = append(p.errs, p.errorAt(keyOffset, "%s", msg))
+		}
+		// Aliases are case-insensitive and, like in Luau, a later definition replaces an earlier one
+		if previous, ok := seen[strings.ToLower(alias)]; ok {
+			delete(aliases, previous)
+		}
+		seen[strings.ToLower(alias)] = alias
+
+		var path string
+		if err := json.Unmarshal(raw, &path); err != nil {
+			p.errs = append(p.errs, p.errorAt(offset, "alias %q must be a string", alias))
+			continue
+		}
+		aliases[alias] = path
+	}
+	if _, err := p.dec.Token(); err != nil {
+		return nil, p.syntaxError(err)
+	}
+	return aliases, nil
+}
+
+// Returns why an alias name is invalid, or "" if it is valid
+func validateAliasName(alias string) string {
+	if alias == "" {
+		return "alias names cannot be empty"
+	}
+	if alias == "." || alias == ".." {
+		return fmt.Sprintf("alias %q cannot be a path component", alias)
+	}
+	for _, c := range alias {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return fmt.Sprintf("alias %q must only contain alphanumeric characters, '-', '_' and '.'", alias)
+		}
+	}
+	return ""
+}
+
+// Returns the sorted names of the aliases of the configuration
+func (c *LuauRC) AliasNames() []string {
+	names := make([]string, 0, len(c.Aliases))
+	for alias := range c.Aliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+	return names
+}
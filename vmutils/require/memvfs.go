@@ -0,0 +1,399 @@
+package require
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The kind of change made to a MemVfs
+type MemVfsOp int
+
+const (
+	MemVfsWrite  MemVfsOp = iota // A file was created or modified
+	MemVfsRemove                 // A file was removed
+	MemVfsRename                 // A file was renamed
+)
+
+func (op MemVfsOp) String() string {
+	switch op {
+	case MemVfsWrite:
+		return "write"
+	case MemVfsRemove:
+		return "remove"
+	case MemVfsRename:
+		return "rename"
+	default:
+		return "unknown"
+	}
+}
+
+// A change made to a MemVfs
+type MemVfsEvent struct {
+	Op      MemVfsOp
+	Path    string // The (absolute) path of the file
+	OldPath string // The previous path of a renamed file
+}
+
+// A MemVfs is an in-memory Vfs that can be modified while in use
+//
+// Files are stored by path and directories are inferred from the paths of the files, so
+// empty directories do not exist. Paths are resolved like in FSVfs, relative to the root of
+// the Vfs. A MemVfs is safe for concurrent use.
+type MemVfs struct {
+	mu    sync.RWMutex
+	files map[string]*memFileData // keyed by fs.FS style path (e.g. "foo/bar.luau")
+
+	listenersMu    sync.Mutex
+	listeners      map[int]func(MemVfsEvent)
+	nextListenerID int
+}
+
+type memFileData struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemVfs creates a new MemVfs with the given files (path -> contents)
+func NewMemVfs(files map[string]string) *MemVfs {
+	v := &MemVfs{
+		files:     make(map[string]*memFileData, len(files)),
+		listeners: make(map[int]func(MemVfsEvent)),
+	}
+	now := time.Now()
+	for name, contents := range files {
+		v.files[memPath(name)] = &memFileData{data: []byte(contents), modTime: now}
+	}
+	return v
+}
+
+// Returns the fs.FS style path of a Vfs path
+func memPath(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Clean(strings.TrimLeft(name, "/"))
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// Returns the absolute Vfs path of a fs.FS style path
+func memAbsPath(name string) string {
+	if name == "." {
+		return "/"
+	}
+	return "/" + name
+}
+
+// Returns whether a fs.FS style path can be used as the name of a file
+func memValidFile(name string) bool {
+	return name != "." && fs.ValidPath(name)
+}
+
+// Returns whether dir is an (inferred) directory. The lock must be held.
+func (v *MemVfs) isDirLocked(dir string) bool {
+	if dir == "." {
+		return true
+	}
+	prefix := dir + "/"
+	for name := range v.files {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// OnChange registers a function to be called after every change to the Vfs, returning a function
+// which unregisters it
+//
+// The function is called synchronously from the goroutine making the change, after the change was made.
+func (v *MemVfs) OnChange(fn func(MemVfsEvent)) func() {
+	v.listenersMu.Lock()
+	defer v.listenersMu.Unlock()
+	id := v.nextListenerID
+	v.nextListenerID++
+	v.listeners[id] = fn
+	return func() {
+		v.listenersMu.Lock()
+		defer v.listenersMu.Unlock()
+		delete(v.listeners, id)
+	}
+}
+
+// Calls the change listeners with events
+func (v *MemVfs) notify(events ...MemVfsEvent) {
+	v.listenersMu.Lock()
+	ids := make([]int, 0, len(v.listeners))
+	for id := range v.listeners {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids) // Call listeners in registration order
+	listeners := make([]func(MemVfsEvent), 0, len(ids))
+	for _, id := range ids {
+		listeners = append(listeners, v.listeners[id])
+	}
+	v.listenersMu.Unlock()
+
+	for _, event := range events {
+		for _, listener := range listeners {
+			listener(event)
+		}
+	}
+}
+
+// WriteFile creates or replaces a file. Parent directories are created implicitly.
+func (v *MemVfs) WriteFile(name string, data []byte) error {
+	p := memPath(name)
+	if !memValidFile(p) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+
+	v.mu.Lock()
+	if v.isDirLocked(p) {
+		v.mu.Unlock()
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+	}
+	// A file cannot be created below another file
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if _, ok := v.files[dir]; ok {
+			v.mu.Unlock()
+			return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+		}
+	}
+	v.files[p] = &memFileData{data: bytes.Clone(data), modTime: time.Now()}
+	v.mu.Unlock()
+
+	v.notify(MemVfsEvent{Op: MemVfsWrite, Path: memAbsPath(p)})
+	return nil
+}
+
+// ReadFile returns the contents of a file
+func (v *MemVfs) ReadFile(name string) ([]byte, error) {
+	p := memPath(name)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	file, ok := v.files[p]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(file.data), nil
+}
+
+// Remove removes a file, or a directory and all files in it
+func (v *MemVfs) Remove(name string) error {
+	p := memPath(name)
+
+	v.mu.Lock()
+	var events []MemVfsEvent
+	if _, ok := v.files[p]; ok {
+		delete(v.files, p)
+		events = append(events, MemVfsEvent{Op: MemVfsRemove, Path: memAbsPath(p)})
+	} else if p != "." && v.isDirLocked(p) {
+		for _, file := range v.filesBelowLocked(p) {
+			delete(v.files, file)
+			events = append(events, MemVfsEvent{Op: MemVfsRemove, Path: memAbsPath(file)})
+		}
+	} else {
+		v.mu.Unlock()
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	v.mu.Unlock()
+
+	v.notify(events...)
+	return nil
+}
+
+// Rename renames (moves) a file or a directory, replacing an existing file at newName
+func (v *MemVfs) Rename(oldName, newName string) error {
+	oldPath, newPath := memPath(oldName), memPath(newName)
+	if !memValidFile(newPath) {
+		return &fs.PathError{Op: "rename", Path: newName, Err: fs.ErrInvalid}
+	}
+
+	v.mu.Lock()
+	var events []MemVfsEvent
+	if file, ok := v.files[oldPath]; ok {
+		if v.isDirLocked(newPath) {
+			v.mu.Unlock()
+			return &fs.PathError{Op: "rename", Path: newName, Err: fs.ErrExist}
+		}
+		delete(v.files, oldPath)
+		v.files[newPath] = file
+		events = append(events, MemVfsEvent{Op: MemVfsRename, Path: memAbsPath(newPath), OldPath: memAbsPath(oldPath)})
+	} else if oldPath != "." && v.isDirLocked(oldPath) {
+		if newPath == oldPath || strings.HasPrefix(newPath, oldPath+"/") {
+			v.mu.Unlock()
+			return &fs.PathError{Op: "rename", Path: newName, Err: fs.ErrInvalid}
+		}
+		if _, ok := v.files[newPath]; ok || v.isDirLocked(newPath) {
+			v.mu.Unlock()
+			return &fs.PathError{Op: "rename", Path: newName, Err: fs.ErrExist}
+		}
+		for _, file := range v.filesBelowLocked(oldPath) {
+			moved := newPath + strings.TrimPrefix(file, oldPath)
+			v.files[moved] = v.files[file]
+			delete(v.files, file)
+			events = append(events, MemVfsEvent{Op: MemVfsRename, Path: memAbsPath(moved), OldPath: memAbsPath(file)})
+		}
+	} else {
+		v.mu.Unlock()
+		return &fs.PathError{Op: "rename", Path: oldName, Err: fs.ErrNotExist}
+	}
+	v.mu.Unlock()
+
+	v.notify(events...)
+	return nil
+}
+
+// Returns the sorted paths of the files below dir. The lock must be held.
+func (v *MemVfs) filesBelowLocked(dir string) []string {
+	prefix := dir + "/"
+	var files []string
+	for name := range v.files {
+		if strings.HasPrefix(name, prefix) {
+			files = append(files, name)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func (v *MemVfs) Open(name string) (fs.File, error) {
+	p := memPath(name)
+	if !fs.ValidPath(p) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if file, ok := v.files[p]; ok {
+		return &memFile{
+			info:   memFileInfo{name: path.Base(p), size: int64(len(file.data)), modTime: file.modTime},
+			Reader: bytes.NewReader(file.data), // Data is never modified in place, so it does not need to be copied
+		}, nil
+	}
+	if v.isDirLocked(p) {
+		entries := v.readDirLocked(p)
+		return &memDir{info: memFileInfo{name: path.Base(p), dir: true}, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (v *MemVfs) ReadDir(name string) ([]fs.DirEntry, error) {
+	p := memPath(name)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if !fs.ValidPath(p) || !v.isDirLocked(p) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return v.readDirLocked(p), nil
+}
+
+// Returns the sorted entries of a directory. The lock must be held.
+func (v *MemVfs) readDirLocked(dir string) []fs.DirEntry {
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	}
+
+	entries := make(map[string]memFileInfo)
+	for name, file := range v.files {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		if child, _, isDir := strings.Cut(rest, "/"); isDir {
+			entries[child] = memFileInfo{name: child, dir: true}
+		} else {
+			entries[child] = memFileInfo{name: child, size: int64(len(file.data)), modTime: file.modTime}
+		}
+	}
+
+	out := make([]fs.DirEntry, 0, len(entries))
+	for _, info := range entries {
+		out = append(out, fs.FileInfoToDirEntry(info))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name() < out[j].Name()
+	})
+	return out
+}
+
+func (v *MemVfs) Cwd() string {
+	return "/"
+}
+
+func (v *MemVfs) Join(paths ...string) string {
+	return unixJoin(paths...)
+}
+
+func (v *MemVfs) NormalizePath(path string) string {
+	return unixnormalizePath(path)
+}
+
+func (v *MemVfs) IsAbsolutePath(path string) bool {
+	return unixisAbsolutePath(path)
+}
+
+// A file opened from a MemVfs
+type memFile struct {
+	*bytes.Reader
+	info memFileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+// A directory opened from a MemVfs
+type memDir struct {
+	info    memFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi memFileInfo) Name() string       { return fi.name }
+func (fi memFileInfo) Size() int64        { return fi.size }
+func (fi memFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi memFileInfo) IsDir() bool        { return fi.dir }
+func (fi memFileInfo) Sys() any           { return nil }
+func (fi memFileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
@@ -0,0 +1,137 @@
+package require
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/koeng101/gluau/vm"
+)
+
+// A NativeLoader returns the loader function of a native (Go implemented) module
+//
+// Like the loader of a Luau module, the returned function is only called the first time the
+// module is required and the value it returns is the result of require.
+type NativeLoader func(cb *vm.CallbackLua) (*vm.LuaFunction, error)
+
+// The prefix of the alias paths used to identify native aliases in JumpToAlias
+const nativeAliasPrefix = "/@native/"
+
+// The state of a SimpleRequirer for native modules
+type nativeModules struct {
+	loaders map[string]NativeLoader // path (without the leading @) -> loader
+	aliases map[string]bool         // aliases with native modules
+
+	active bool     // Whether the current context is inside of a native alias
+	path   []string // The current path inside of the native alias (starting with the alias)
+}
+
+// RegisterNativeModule registers a native module which can be required using path (e.g. "@std/json")
+//
+// The alias of a native module (e.g. "std") is reserved for native modules: it takes precedence over
+// aliases of the same name in .luaurc files and filesystem modules are never resolved through it.
+// Native modules are cached by require like other modules using the cache key
+// "<cachePrefix>@native:<path>", which cannot clash with the cache keys of filesystem modules.
+// A path may be both a module and the parent of other modules (e.g. "@std/json" and "@std/json/pretty").
+func (r *SimpleRequirer) RegisterNativeModule(path string, loader NativeLoader) error {
+	if loader == nil {
+		return errors.New("native module loader cannot be nil")
+	}
+	components, err := splitNativePath(path)
+	if err != nil {
+		return err
+	}
+
+	if r.native.loaders == nil {
+		r.native.loaders = make(map[string]NativeLoader)
+		r.native.aliases = make(map[string]bool)
+	}
+	key := strings.Join(components, "/")
+	if _, ok := r.native.loaders[key]; ok {
+		return fmt.Errorf("native module %s is already registered", path)
+	}
+	r.native.loaders[key] = loader
+	r.native.aliases[components[0]] = true
+	return nil
+}
+
+// Splits the path of a native module into its alias and module components
+func splitNativePath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "@") {
+		return nil, fmt.Errorf("native module path %q must start with an alias (e.g. @std/json)", path)
+	}
+	components := strings.Split(path[1:], "/")
+	if len(components) < 2 {
+		return nil, fmt.Errorf("native module path %q must contain a module name after the alias", path)
+	}
+	for _, component := range components {
+		if component == "" || component == "." || component == ".." || strings.Contains(component, "@") {
+			return nil, fmt.Errorf("invalid native module path %q", path)
+		}
+	}
+	return components, nil
+}
+
+// Returns whether there are any native modules
+func (n *nativeModules) enabled() bool {
+	return len(n.loaders) != 0
+}
+
+// Returns the current path as a registered path
+func (n *nativeModules) currentPath() string {
+	return strings.Join(n.path, "/")
+}
+
+// Returns whether a registered path is or is below the given path
+func (n *nativeModules) exists(path string) bool {
+	if _, ok := n.loaders[path]; ok {
+		return true
+	}
+	for registered := range n.loaders {
+		if strings.HasPrefix(registered, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *nativeModules) jumpToAlias(path string) bool {
+	alias, ok := strings.CutPrefix(path, nativeAliasPrefix)
+	if !ok || !n.aliases[alias] {
+		n.active = false
+		return false
+	}
+	n.active = true
+	n.path = []string{alias}
+	return true
+}
+
+func (n *nativeModules) toParent() *vm.NavigationResult {
+	if len(n.path) <= 1 {
+		return vm.NotFoundNavigationResult()
+	}
+	n.path = n.path[:len(n.path)-1]
+	return nil
+}
+
+func (n *nativeModules) toChild(name string) *vm.NavigationResult {
+	if !n.exists(n.currentPath() + "/" + name) {
+		return vm.NotFoundNavigationResult()
+	}
+	n.path = append(n.path, name)
+	return nil
+}
+
+func (n *nativeModules) hasModule() bool {
+	_, ok := n.loaders[n.currentPath()]
+	return ok
+}
+
+// Returns the configuration with the native aliases added
+func (n *nativeModules) injectAliases(config []byte) ([]byte, error) {
+	aliases := make(map[string]string, len(n.aliases))
+	for alias := range n.aliases {
+		aliases[alias] = nativeAliasPrefix + alias
+	}
+	return InjectAliases(config, aliases, true)
+}
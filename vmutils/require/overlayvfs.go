@@ -0,0 +1,91 @@
+package require
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+)
+
+// An OverlayVfs layers multiple Vfs's on top of each other (e.g. user scripts over built-in libraries)
+//
+// Layers are searched in the order they were given, so earlier layers take precedence: a path
+// is opened from the first layer containing it (as a file or a directory), and directory listings
+// are the union of the directories of all layers, with entries of earlier layers hiding entries
+// of the same name in later layers. Path handling (Cwd, Join, NormalizePath and IsAbsolutePath)
+// is done by the first layer.
+//
+// As module resolution sees the union of all layers, "foo.luau" in one layer and "foo.lua" in
+// another make the module "foo" ambiguous.
+type OverlayVfs struct {
+	layers []Vfs
+}
+
+// NewOverlayVfs creates a new OverlayVfs. At least one layer must be given.
+func NewOverlayVfs(layers ...Vfs) (*OverlayVfs, error) {
+	if len(layers) == 0 {
+		return nil, errors.New("an overlay vfs needs at least one layer")
+	}
+	return &OverlayVfs{layers: layers}, nil
+}
+
+func (v *OverlayVfs) Open(name string) (fs.File, error) {
+	var firstErr error
+	for _, layer := range v.layers {
+		file, err := layer.Open(name)
+		if err == nil {
+			return file, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+func (v *OverlayVfs) ReadDir(name string) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	seen := make(map[string]bool)
+	found := false
+	var firstErr error
+	for _, layer := range v.layers {
+		layerEntries, err := layer.ReadDir(name)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		found = true
+		for _, entry := range layerEntries {
+			if seen[entry.Name()] {
+				continue
+			}
+			seen[entry.Name()] = true
+			entries = append(entries, entry)
+		}
+	}
+	if !found {
+		return nil, firstErr
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+func (v *OverlayVfs) Cwd() string {
+	return v.layers[0].Cwd()
+}
+
+func (v *OverlayVfs) Join(paths ...string) string {
+	return v.layers[0].Join(paths...)
+}
+
+func (v *OverlayVfs) NormalizePath(path string) string {
+	return v.layers[0].NormalizePath(path)
+}
+
+func (v *OverlayVfs) IsAbsolutePath(path string) bool {
+	return v.layers[0].IsAbsolutePath(path)
+}
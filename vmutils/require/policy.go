@@ -0,0 +1,169 @@
+package require
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/koeng101/gluau/vm"
+)
+
+// A RequirePolicy decides which modules may be required through a SimpleRequirer
+//
+// Returning an error denies the require, which fails with a denied navigation result
+// (see vm.DeniedNavigationResult) using the error as its reason.
+type RequirePolicy interface {
+	// CheckRequirer is called with the chunk name of the module starting a require
+	CheckRequirer(chunkName string) error
+
+	// CheckAlias is called for every alias defined by a configuration file (.luaurc) and for
+	// native module aliases. Denied aliases fail when they are used.
+	//
+	// The built-in @self alias is resolved by Luau itself and is not checked.
+	CheckAlias(alias string) error
+
+	// CheckModule is called with the absolute path of every module file navigated to, or with
+	// the path of native modules (e.g. "@std/json")
+	CheckModule(path string) error
+}
+
+// PolicyRules is a RequirePolicy denying requires based on a set of rules
+//
+// Patterns use the syntax of path.Match and also match everything below a matching directory
+// (e.g. "/secrets" matches "/secrets/keys.luau").
+type PolicyRules struct {
+	// Patterns of chunk names which may not require any module
+	DeniedRequirers []string
+
+	// If set, only modules matching one of the patterns may be required
+	AllowedModules []string
+
+	// Patterns of modules which may not be required
+	DeniedModules []string
+
+	// Aliases which may not be used (compared case-insensitively like Luau does)
+	DeniedAliases []string
+
+	// If set, only modules inside of the directory (e.g. "/sandbox") may be required,
+	// so requires cannot escape it using ../
+	//
+	// Native modules are not affected by Root.
+	Root string
+}
+
+func (p *PolicyRules) CheckRequirer(chunkName string) error {
+	if pattern, ok := matchAny(p.DeniedRequirers, chunkName); ok {
+		return fmt.Errorf("%s may not require modules (matches %s)", chunkName, pattern)
+	}
+	return nil
+}
+
+func (p *PolicyRules) CheckAlias(alias string) error {
+	for _, denied := range p.DeniedAliases {
+		if strings.EqualFold(strings.TrimPrefix(denied, "@"), alias) {
+			return fmt.Errorf("alias @%s may not be used", alias)
+		}
+	}
+	return nil
+}
+
+func (p *PolicyRules) CheckModule(modulePath string) error {
+	if p.Root != "" && !strings.HasPrefix(modulePath, "@") && !isWithin(modulePath, p.Root) {
+		return fmt.Errorf("%s is outside of %s", modulePath, path.Clean(p.Root))
+	}
+	if pattern, ok := matchAny(p.DeniedModules, modulePath); ok {
+		return fmt.Errorf("%s may not be required (matches %s)", modulePath, pattern)
+	}
+	if len(p.AllowedModules) > 0 {
+		if _, ok := matchAny(p.AllowedModules, modulePath); !ok {
+			return fmt.Errorf("%s is not an allowed module", modulePath)
+		}
+	}
+	return nil
+}
+
+// Returns the first pattern matching name or one of its parent directories
+func matchAny(patterns []string, name string) (string, bool) {
+	for _, pattern := range patterns {
+		for current := name; ; current = path.Dir(current) {
+			if ok, _ := path.Match(pattern, current); ok {
+				return pattern, true
+			}
+			if current == "/" || current == "." || !strings.Contains(current, "/") {
+				break
+			}
+		}
+	}
+	return "", false
+}
+
+// Returns whether p is dir or inside of dir
+func isWithin(p, dir string) bool {
+	p, dir = path.Clean(p), path.Clean(dir)
+	return dir == "/" || p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// The prefix of the alias paths denied aliases are replaced with in configuration files
+const deniedAliasPrefix = "/@denied/"
+
+// SetPolicy sets the policy of the requirer, nil allows all requires (the default)
+func (r *SimpleRequirer) SetPolicy(policy RequirePolicy) {
+	r.policy = policy
+}
+
+// Returns a denied navigation result if the policy denies the current module
+func (r *SimpleRequirer) checkModule() *vm.NavigationResult {
+	if r.policy == nil {
+		return nil
+	}
+	var modulePath string
+	if r.native.active {
+		if !r.native.hasModule() {
+			return nil
+		}
+		modulePath = "@" + r.native.currentPath()
+	} else {
+		if !vfsIsFile(r.vfs.fs, r.vfs.getAbsoluteFilePath()) {
+			return nil // Directories are only navigated through
+		}
+		modulePath = r.vfs.getAbsoluteFilePath()
+	}
+	if err := r.policy.CheckModule(modulePath); err != nil {
+		return vm.DeniedNavigationResult(err.Error())
+	}
+	return nil
+}
+
+// Replaces the aliases of a configuration file denied by the policy
+func (r *SimpleRequirer) applyAliasPolicy(config []byte) ([]byte, error) {
+	if r.policy == nil {
+		return config, nil
+	}
+	aliases, err := configAliases(config)
+	if err != nil {
+		return config, nil // Invalid configurations are left as-is so Luau reports the error
+	}
+	denied := make(map[string]string)
+	for _, alias := range aliases {
+		if err := r.policy.CheckAlias(alias); err != nil {
+			r.deniedAliases[strings.ToLower(alias)] = err.Error()
+			denied[alias] = deniedAliasPrefix + strings.ToLower(alias)
+		}
+	}
+	if len(denied) == 0 {
+		return config, nil
+	}
+	return InjectAliases(config, denied, true)
+}
+
+// Returns a denied navigation result if path is the path of a denied alias
+func (r *SimpleRequirer) deniedAlias(aliasPath string) *vm.NavigationResult {
+	alias, ok := strings.CutPrefix(aliasPath, deniedAliasPrefix)
+	if !ok {
+		return nil
+	}
+	if reason, ok := r.deniedAliases[alias]; ok {
+		return vm.DeniedNavigationResult(reason)
+	}
+	return vm.DeniedNavigationResult("alias @" + alias + " may not be used")
+}
@@ -3,6 +3,7 @@ package require
 import (
 	"fmt"
 	"io"
+	"sync/atomic"
 
 	"github.com/koeng101/gluau/vm"
 )
@@ -12,6 +13,13 @@ type SimpleRequirer struct {
 	vfs         *vfsNavigator
 	globalTable *vm.LuaTable
 	debug       bool
+	native      nativeModules
+	requirer    string // The absolute file path of the module currently requiring a module
+	deps        *dependencyTracker
+	watcher     atomic.Pointer[Watcher]
+
+	policy        RequirePolicy
+	deniedAliases map[string]string // lowercased alias -> reason, for aliases denied by the policy
 }
 
 func NewSimpleRequirer(cachePrefix string, globalTable *vm.LuaTable, vfs Vfs, debug bool) *SimpleRequirer {
@@ -20,6 +28,9 @@ func NewSimpleRequirer(cachePrefix string, globalTable *vm.LuaTable, vfs Vfs, de
 		vfs:         newVfsNavigator(vfs),
 		globalTable: globalTable,
 		debug:       debug,
+		deps:        newDependencyTracker(),
+
+		deniedAliases: make(map[string]string),
 	}
 }
 
@@ -35,58 +46,160 @@ func (r *SimpleRequirer) IsRequireAllowed(chunkName string) bool {
 
 func (r *SimpleRequirer) Reset(chunkName string) *vm.NavigationResult {
 	r.debugPrint("Resetting require with chunk name:", chunkName)
+	r.native.active = false
+	r.requirer = ""
+	if r.policy != nil {
+		if err := r.policy.CheckRequirer(chunkName); err != nil {
+			return vm.DeniedNavigationResult(err.Error())
+		}
+	}
+
+	// Evict changed modules before they are looked up in the require cache
+	if w := r.watcher.Load(); w != nil && w.lua != nil {
+		if _, err := w.Flush(); err != nil {
+			return vm.OtherNavigationResult(err)
+		}
+	}
 	if chunkName == "=repl" {
 		return r.vfs.resetToStdin()
 	}
 
-	return r.vfs.resetToPath(chunkName)
+	result := r.vfs.resetToPath(chunkName)
+	if result == nil {
+		r.requirer = r.vfs.getAbsoluteFilePath()
+	}
+	return result
 }
 
 func (r *SimpleRequirer) JumpToAlias(path string) *vm.NavigationResult {
 	r.debugPrint("Jumping to alias:", path)
+	if denied := r.deniedAlias(path); denied != nil {
+		return denied
+	}
+	if r.native.jumpToAlias(path) {
+		return nil
+	}
 	if !r.vfs.fs.IsAbsolutePath(path) {
 		return vm.NotFoundNavigationResult()
 	}
 
-	return r.vfs.resetToPath(path)
+	if result := r.vfs.resetToPath(path); result != nil {
+		return result
+	}
+	return r.checkModule()
 }
 
 func (r *SimpleRequirer) ToParent() *vm.NavigationResult {
 	r.debugPrint("Navigating to parent directory")
+	if r.native.active {
+		return r.native.toParent()
+	}
 	return r.vfs.toParent()
 }
 
 func (r *SimpleRequirer) ToChild(name string) *vm.NavigationResult {
 	r.debugPrint("Navigating to child:", name)
-	return r.vfs.toChild(name)
+	var result *vm.NavigationResult
+	if r.native.active {
+		result = r.native.toChild(name)
+	} else {
+		result = r.vfs.toChild(name)
+	}
+	if result != nil {
+		return result
+	}
+	return r.checkModule()
 }
 
 func (r *SimpleRequirer) HasModule() bool {
-	r.debugPrint("Checking if module exists at:", r.vfs.getFilePath())
-	return vfsIsFile(r.vfs.fs, r.vfs.getFilePath())
+	if r.native.active {
+		return r.native.hasModule()
+	}
+	r.debugPrint("Checking if module exists at:", r.vfs.getAbsoluteFilePath())
+	return vfsIsFile(r.vfs.fs, r.vfs.getAbsoluteFilePath())
+}
+
+// ModuleKey returns the cache key of the current module without recording that it was required
+func (r *SimpleRequirer) ModuleKey() string {
+	if r.native.active {
+		return r.cachePrefix + "@native:@" + r.native.currentPath()
+	}
+	return r.moduleCacheKey(r.vfs.getAbsoluteFilePath())
 }
 
 func (r *SimpleRequirer) CacheKey() string {
 	r.debugPrint("Generating cache key for:", r.vfs.getAbsoluteFilePath())
-	return r.cachePrefix + "@" + r.vfs.getAbsoluteFilePath()
+	key := r.ModuleKey()
+	if r.requirer != "" {
+		r.deps.graph.AddEdge(r.moduleCacheKey(r.requirer), key)
+	}
+	return key
 }
 
 func (r *SimpleRequirer) HasConfig() bool {
-	r.debugPrint("Checking if config exists at:", r.vfs.getLuaurcPath())
-	return vfsIsFile(r.vfs.fs, r.vfs.getLuaurcPath())
+	if r.native.active {
+		return false
+	}
+	luaurcPath := r.vfs.getLuaurcPath()
+	r.debugPrint("Checking if config exists at:", luaurcPath)
+	if vfsIsFile(r.vfs.fs, luaurcPath) {
+		return true
+	}
+	// Record the missing configuration so that creating it is detected by a Watcher
+	r.deps.setMissing(luaurcPath)
+	r.track(luaurcPath)
+	// The native aliases are provided at the root even if there is no configuration file there.
+	// Only the root is used as Luau stops searching upwards at the first configuration, which
+	// would hide the aliases of configuration files in parent directories.
+	return r.native.enabled() && r.vfs.isAtRoot()
+}
+
+// IsAtRoot returns whether the current module is the root directory of the Vfs (see RootReporter)
+func (r *SimpleRequirer) IsAtRoot() bool {
+	return !r.native.active && r.vfs.isAtRoot()
 }
 
 func (r *SimpleRequirer) Config() ([]byte, error) {
 	r.debugPrint("Reading config from:", r.vfs.getLuaurcPath())
-	file, err := r.vfs.fs.Open(r.vfs.getLuaurcPath())
-	if err != nil {
-		return nil, err
+	var config []byte
+	if vfsIsFile(r.vfs.fs, r.vfs.getLuaurcPath()) {
+		file, err := r.vfs.fs.Open(r.vfs.getLuaurcPath())
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		config, err = io.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+
+		r.deps.setLoaded(r.vfs.getLuaurcPath(), config)
+		r.track(r.vfs.getLuaurcPath())
+
+		// Luau decides whether the configuration is valid, ParseLuauRC is only used for diagnostics
+		if _, err := ParseLuauRC(r.vfs.getLuaurcPath(), config); err != nil {
+			r.debugPrint("Configuration failed validation:", err)
+		}
 	}
-	defer file.Close()
-	return io.ReadAll(file)
+
+	if r.native.enabled() {
+		injected, err := r.native.injectAliases(config)
+		if err != nil {
+			return config, nil // Invalid configurations are left as-is so Luau reports the error
+		}
+		config = injected
+	}
+	return r.applyAliasPolicy(config)
 }
 
 func (r *SimpleRequirer) Loader(cb *vm.CallbackLua) (*vm.LuaFunction, error) {
+	if denied := r.checkModule(); denied != nil {
+		return nil, denied.Err()
+	}
+	if r.native.active {
+		r.debugPrint("Loading native module:", r.native.currentPath())
+		return r.native.loaders[r.native.currentPath()](cb)
+	}
 	r.debugPrint("Loading module from:", r.vfs.getFilePath())
 	chunkname := r.vfs.getAbsoluteFilePath()
 	file, err := r.vfs.fs.Open(chunkname)
@@ -98,6 +211,8 @@ func (r *SimpleRequirer) Loader(cb *vm.CallbackLua) (*vm.LuaFunction, error) {
 	if err != nil {
 		return nil, err
 	}
+	r.deps.setLoaded(chunkname, content)
+	r.track(chunkname)
 
 	return cb.MainState().LoadChunk(vm.ChunkOpts{
 		Name: chunkname,
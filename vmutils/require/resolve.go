@@ -0,0 +1,212 @@
+package require
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/koeng101/gluau/vm"
+)
+
+// A Resolution describes how a require string was resolved to a module
+type Resolution struct {
+	Requirer string   // The chunk name of the requiring module
+	Require  string   // The require string
+	Path     string   // The absolute path of the resolved module, empty if resolution failed
+	Steps    []string // Human readable descriptions of each resolution step
+}
+
+func (r *Resolution) step(format string, args ...any) {
+	r.Steps = append(r.Steps, fmt.Sprintf(format, args...))
+}
+
+// Explain returns the steps of the resolution as a numbered list
+func (r *Resolution) Explain() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "require(%q) from %s\n", r.Require, r.Requirer)
+	for i, step := range r.Steps {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, step)
+	}
+	return b.String()
+}
+
+// Resolve resolves a require string the way Luau require-by-string does, recording each step
+//
+// requirer is the chunk name of the requiring module, an absolute path (or a path relative to the
+// current directory of vfs). Resolution navigates vfs exactly like SimpleRequirer does, so modules
+// are ambiguous (or not found) in the same cases. The resolution is returned even if resolution
+// fails, with the steps up to the failure.
+func Resolve(vfs Vfs, requirer, require string) (*Resolution, error) {
+	r := &resolver{
+		res: &Resolution{Requirer: requirer, Require: require},
+		nav: newVfsNavigator(vfs),
+	}
+	err := r.resolve()
+	if err != nil {
+		r.res.step("%s", err)
+	}
+	return r.res, err
+}
+
+// Drives a vfsNavigator through the resolution of a require string, recording each step
+type resolver struct {
+	res *Resolution
+	nav *vfsNavigator
+}
+
+func (r *resolver) resolve() error {
+	if err := r.navErr(r.nav.resetToPath(r.res.Requirer), r.res.Requirer); err != nil {
+		return err
+	}
+	r.res.step("the requiring module is %s (%s)", r.nav.absoluteModulePath, r.nav.getAbsoluteFilePath())
+
+	require := r.res.Require
+	first, rest, _ := strings.Cut(require, "/")
+	switch {
+	case first == "." || first == "..":
+		// Relative paths start at the parent of the requiring module, .. components navigate further up
+		if err := r.toParent(); err != nil {
+			return err
+		}
+		rest = require
+	case strings.EqualFold(first, "@self"):
+		r.res.step("@self refers to the requiring module itself")
+	case strings.HasPrefix(first, "@"):
+		if err := r.toAlias(first[1:]); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("require path must start with a valid prefix: ./, ../, or @")
+	}
+
+	if err := r.navigatePath(rest); err != nil {
+		return err
+	}
+
+	path := r.nav.getAbsoluteFilePath()
+	if !vfsIsFile(r.nav.fs, path) {
+		return fmt.Errorf("could not resolve %q: %s is not a module", require, path)
+	}
+	r.res.Path = path
+	r.res.step("resolved to %s", r.res.Path)
+	return nil
+}
+
+// Navigates through the components of a relative path from the current module
+func (r *resolver) navigatePath(path string) error {
+	if path == "" {
+		return nil
+	}
+	for _, component := range strings.Split(path, "/") {
+		switch component {
+		case ".", "":
+		case "..":
+			if err := r.toParent(); err != nil {
+				return err
+			}
+		default:
+			target := r.nav.fs.NormalizePath(r.nav.fs.Join(r.nav.absoluteModulePath, component))
+			if err := r.navErr(r.nav.toChild(component), target); err != nil {
+				return err
+			}
+			r.res.step("navigated to %s (%s)", r.nav.absoluteModulePath, r.nav.getAbsoluteFilePath())
+		}
+	}
+	return nil
+}
+
+func (r *resolver) toParent() error {
+	from := r.nav.absoluteModulePath
+	if err := r.navErr(r.nav.toParent(), "the parent of "+from); err != nil {
+		return err
+	}
+	r.res.step("navigated to the parent of %s, %s", from, r.nav.absoluteModulePath)
+	return nil
+}
+
+// Navigates to an alias by looking for it in the configuration files above the requiring module
+func (r *resolver) toAlias(alias string) error {
+	module := r.nav.absoluteModulePath
+	for {
+		from := r.nav.absoluteModulePath
+		if result := r.nav.toParent(); result != nil {
+			if result.IsNotFound() {
+				break // The root has been reached
+			}
+			return r.navErr(result, "the parent of "+from)
+		}
+
+		configPath := r.nav.getLuaurcPath()
+		if !vfsIsFile(r.nav.fs, configPath) {
+			continue
+		}
+		rc, err := readLuauRC(r.nav.fs, configPath)
+		if err != nil {
+			return err
+		}
+		aliasPath, ok := rc.Alias(alias)
+		if !ok {
+			r.res.step("%s does not define @%s", configPath, alias)
+			continue
+		}
+
+		r.res.step("@%s is defined as %q in %s", alias, aliasPath, configPath)
+		if r.nav.fs.IsAbsolutePath(aliasPath) {
+			if err := r.navErr(r.nav.resetToPath(aliasPath), aliasPath); err != nil {
+				return err
+			}
+			r.res.step("jumped to %s (%s)", r.nav.absoluteModulePath, r.nav.getAbsoluteFilePath())
+			return nil
+		}
+		// Relative aliases are relative to the directory of the configuration file
+		return r.navigatePath(aliasPath)
+	}
+	return fmt.Errorf("@%s is not defined in any .luaurc above %s", alias, module)
+}
+
+// Returns the error of a failed navigation to target
+func (r *resolver) navErr(result *vm.NavigationResult, target string) error {
+	switch {
+	case result == nil:
+		return nil
+	case result.IsAmbiguous():
+		return fmt.Errorf("could not resolve %q: module is ambiguous at %s", r.res.Require, target)
+	case result.IsNotFound():
+		return fmt.Errorf("could not resolve %q: no module found at %s", r.res.Require, target)
+	default:
+		return fmt.Errorf("could not resolve %q: %w", r.res.Require, result.Err())
+	}
+}
+
+// Reads and parses a configuration file from vfs
+func readLuauRC(vfs Vfs, path string) (*LuauRC, error) {
+	file, err := vfs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLuauRC(path, data)
+}
+
+// Explain resolves a require string from requirer like the requirer does, including
+// native modules, and describes each step (see Resolve)
+func (r *SimpleRequirer) Explain(requirer, require string) (*Resolution, error) {
+	if alias, rest, ok := strings.Cut(require, "/"); ok && strings.HasPrefix(alias, "@") && r.native.aliases[alias[1:]] {
+		res := &Resolution{Requirer: requirer, Require: require}
+		res.step("@%s is reserved for native modules", alias[1:])
+		key := alias[1:] + "/" + rest
+		if _, ok := r.native.loaders[key]; !ok {
+			err := fmt.Errorf("could not resolve %q: no native module %s is registered", require, require)
+			res.step("%s", err)
+			return res, err
+		}
+		res.Path = "@" + key
+		res.step("resolved to the native module %s", res.Path)
+		return res, nil
+	}
+	return Resolve(r.vfs.fs, requirer, require)
+}
@@ -2,7 +2,11 @@
 package require
 
 import (
+	"fmt"
 	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
 	"strings"
 )
 
@@ -17,16 +21,48 @@ type Vfs interface {
 
 // A UnixVfs is a Vfs implementation that uses the standard library's ReadDirFS
 // and normalizes paths/handles absolute paths in a Unix-like manner.
+//
+// Both '/' and '\\' are accepted as separators and Windows volume names (e.g. "C:") are
+// kept as part of absolute paths. Relative paths (such as relative chunk names) are
+// resolved against the working directory, which is "/" unless set using SetCwd.
 type UnixVfs struct {
-	fs fs.ReadDirFS
+	fs  fs.ReadDirFS
+	cwd string
 }
 
 func NewUnixVfs(fs fs.ReadDirFS) *UnixVfs {
-	return &UnixVfs{fs: fs}
+	return &UnixVfs{fs: fs, cwd: "/"}
+}
+
+// NewDirVfs creates a UnixVfs backed by os.DirFS, rooted at the real directory dir
+//
+// The absolute path "/" of the Vfs refers to dir and paths cannot escape it.
+func NewDirVfs(dir string) (*UnixVfs, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewUnixVfs(&rootedFS{fs: os.DirFS(abs), dir: abs}), nil
+}
+
+// SetCwd sets the working directory relative paths are resolved against
+//
+// A relative working directory is resolved against "/".
+func (v *UnixVfs) SetCwd(cwd string) {
+	if !unixisAbsolutePath(cwd) {
+		cwd = unixJoin("/", cwd)
+	}
+	v.cwd = unixnormalizePath(cwd)
 }
 
 func (v *UnixVfs) Open(name string) (fs.File, error) {
-	//fmt.Println("Opening file:", name)
 	return v.fs.Open(name)
 }
 
@@ -34,14 +70,17 @@ func (v *UnixVfs) ReadDir(name string) ([]fs.DirEntry, error) {
 	return v.fs.ReadDir(name)
 }
 
+// Cwd returns the working directory relative paths are resolved against
 func (v *UnixVfs) Cwd() string {
-	// Return the current working directory
-	return "" // TODO: Support getting the current working directory
+	if v.cwd == "" {
+		return "/"
+	}
+	return v.cwd
 }
 
 func (v *UnixVfs) Join(paths ...string) string {
 	// Join the given paths using the VFS's path separator
-	return strings.Join(paths, "/")
+	return unixJoin(paths...)
 }
 
 func (v *UnixVfs) NormalizePath(path string) string {
@@ -54,8 +93,18 @@ func (v *UnixVfs) IsAbsolutePath(path string) bool {
 
 func vfsIsFile(vfs Vfs, path string) bool {
 	// Check if the file exists in the VFS
-	_, err := vfs.Open(path)
-	return err == nil
+	file, err := vfs.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	// Most fs.FS implementations can open directories too
+	info, err := file.Stat()
+	if err != nil {
+		return true // Assume a file if it cannot be stat'ed
+	}
+	return !info.IsDir()
 }
 
 func vfsIsDir(vfs Vfs, path string) bool {
@@ -63,3 +112,44 @@ func vfsIsDir(vfs Vfs, path string) bool {
 	_, err := vfs.ReadDir(path)
 	return err == nil
 }
+
+// A rootedFS exposes an fs.FS using Vfs paths, which may be absolute and use Windows separators
+type rootedFS struct {
+	fs  fs.FS
+	dir string // The real directory of fs if it is backed by the OS, used to watch for changes
+}
+
+// Returns the fs.FS path of a Vfs path
+func (r *rootedFS) fsPath(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if len(name) >= 2 && name[1] == ':' && unixisAbsolutePath(name) {
+		name = name[2:] // Strip the volume name
+	}
+	name = path.Clean("/" + name)
+	if name == "/" {
+		return "."
+	}
+	return name[1:]
+}
+
+// Returns the OS path of a Vfs path
+func (r *rootedFS) osPath(name string) string {
+	return filepath.Join(r.dir, filepath.FromSlash(r.fsPath(name)))
+}
+
+// Returns the Vfs path of an OS path, or false if it is outside of the directory
+func (r *rootedFS) vfsPath(name string) (string, bool) {
+	rel, err := filepath.Rel(r.dir, name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return path.Clean("/" + filepath.ToSlash(rel)), true
+}
+
+func (r *rootedFS) Open(name string) (fs.File, error) {
+	return r.fs.Open(r.fsPath(name))
+}
+
+func (r *rootedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(r.fs, r.fsPath(name))
+}
@@ -160,20 +160,18 @@ func (v *vfsNavigator) getModulePath(filePath string) (string, string) {
 }
 
 func (v *vfsNavigator) updateRealPaths() *vm.NavigationResult {
-	result := v.getRealPath(v.modulePath)
+	// Only the absolute path is looked up, as relative paths are relative to the working
+	// directory of the Vfs rather than the root of its file system
 	absoluteResult := v.getRealPath(v.absoluteModulePath)
-	if result.status != nil || absoluteResult.status != nil {
-		return result.status
-	}
-
-	if result.realPath == nil {
-		return vm.OtherStringNavigationResult("result.realPath is nil")
+	if absoluteResult.status != nil {
+		return absoluteResult.status
 	}
 	if absoluteResult.realPath == nil {
 		return vm.OtherStringNavigationResult("absoluteResult.realPath is nil")
 	}
-	resultRealPath := *result.realPath
 	absoluteResultRealPath := *absoluteResult.realPath
+	resultRealPath := v.modulePath + strings.TrimPrefix(absoluteResultRealPath, v.absoluteModulePath)
+
 	if v.fs.IsAbsolutePath(resultRealPath) {
 		v.realPath = v.absolutePathPrefix + resultRealPath
 	} else {
@@ -260,6 +258,11 @@ func (v *vfsNavigator) toChild(name string) *vm.NavigationResult {
 	return v.updateRealPaths()
 }
 
+// Returns whether the navigator is at the root directory of the Vfs
+func (v *vfsNavigator) isAtRoot() bool {
+	return v.absoluteModulePath == "" || v.absoluteModulePath == "/"
+}
+
 func (v *vfsNavigator) getFilePath() string {
 	return v.realPath
 }
@@ -268,8 +271,12 @@ func (v *vfsNavigator) getAbsoluteFilePath() string {
 	return v.absoluteRealPath
 }
 
+// Returns the path of the configuration file of the current module
+//
+// The absolute path is used so that configuration files are found relative to the working
+// directory of the Vfs rather than the root of its file system.
 func (v *vfsNavigator) getLuaurcPath() string {
-	directory := v.realPath
+	directory := v.absoluteRealPath
 
 	for _, suffix := range INIT_SUFFIXES {
 		if strings.HasSuffix(directory, suffix) {
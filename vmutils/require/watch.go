@@ -0,0 +1,388 @@
+package require
+
+import (
+	"hash/fnv"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/koeng101/gluau/vm"
+)
+
+// Tracks which modules required which modules and the contents of loaded modules
+type dependencyTracker struct {
+	graph *DependencyGraph // cache key -> cache keys of the modules it required
+
+	mu     sync.Mutex
+	loaded map[string]uint64 // path of loaded modules and .luaurc files -> hash of their contents (missingHash if missing)
+}
+
+// The hash recorded for .luaurc files that were looked up but do not exist
+const missingHash = 0
+
+func newDependencyTracker() *dependencyTracker {
+	return &dependencyTracker{
+		graph:  NewDependencyGraph(),
+		loaded: make(map[string]uint64),
+	}
+}
+
+func hashContents(content []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(content)
+	return h.Sum64()
+}
+
+// Records the contents of a loaded module or .luaurc file
+func (d *dependencyTracker) setLoaded(path string, content []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loaded[path] = hashContents(content)
+}
+
+// Records that a .luaurc file was looked up but does not exist, so that creating it is detected
+func (d *dependencyTracker) setMissing(path string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loaded[path] = missingHash
+}
+
+// Returns whether the file at path is loaded (or was looked up, for .luaurc files)
+func (d *dependencyTracker) isLoaded(path string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.loaded[path]
+	return ok
+}
+
+// Forgets the contents of the given paths, or of all files if all is set
+func (d *dependencyTracker) unload(paths []string, all bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if all {
+		d.loaded = make(map[string]uint64)
+		return
+	}
+	for _, path := range paths {
+		delete(d.loaded, path)
+	}
+}
+
+// Returns the paths and content hashes of the loaded modules and .luaurc files
+func (d *dependencyTracker) loadedModules() map[string]uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make(map[string]uint64, len(d.loaded))
+	for path, hash := range d.loaded {
+		out[path] = hash
+	}
+	return out
+}
+
+// Returns the cache key of the module with the given absolute file path
+func (r *SimpleRequirer) moduleCacheKey(path string) string {
+	return r.cachePrefix + "@" + path
+}
+
+// Watches a loaded file if a Watcher is active
+func (r *SimpleRequirer) track(path string) {
+	if w := r.watcher.Load(); w != nil {
+		w.track(path)
+	}
+}
+
+// Dependents returns the cache keys of the modules that required the module with the given
+// cache key, as recorded by the requirer
+func (r *SimpleRequirer) Dependents(key string) []string {
+	return r.deps.graph.Dependents(key)
+}
+
+// Graph returns the graph of the modules required through the requirer, which Invalidate and
+// Watcher use to find the modules to evict
+//
+// Nodes are the cache keys of modules. Unlike with a DependencyRecorder, requires from the REPL
+// are not recorded. The dependencies of evicted modules are recorded again when they are
+// required again.
+func (r *SimpleRequirer) Graph() *DependencyGraph {
+	return r.deps.graph
+}
+
+// Invalidate evicts the modules at the given (absolute) file paths from the require cache of lua,
+// along with all modules that (transitively) required them, returning the evicted cache keys
+//
+// If a path is a .luaurc file, all modules of this requirer are evicted as aliases may now resolve
+// differently. Invalidate must not be called concurrently with code running in lua.
+func (r *SimpleRequirer) Invalidate(lua *vm.Lua, paths ...string) ([]string, error) {
+	var keys []string
+	for _, path := range paths {
+		if path == ".luaurc" || strings.HasSuffix(path, "/.luaurc") {
+			return r.evict(lua, nil, true)
+		}
+		keys = append(keys, r.moduleCacheKey(path))
+	}
+	return r.evict(lua, keys, false)
+}
+
+// Evicts keys and their dependents (or all modules of the requirer) from the require cache
+//
+// The contents of evicted modules are forgotten, so that deleted files are not reported as changed
+// again. They are recorded again when the modules are loaded again.
+func (r *SimpleRequirer) evict(lua *vm.Lua, keys []string, all bool) ([]string, error) {
+	cache := lua.RequireCache()
+	if all {
+		evicted, err := cache.EvictPrefix(r.cachePrefix + "@")
+		for _, key := range evicted {
+			r.deps.graph.removeDependencies(key)
+		}
+		r.deps.unload(nil, true)
+		return evicted, err
+	}
+
+	var evicted []string
+	var paths []string
+	for _, key := range r.deps.graph.TransitiveDependents(keys...) {
+		ok, err := cache.Evict(key)
+		if err != nil {
+			r.deps.unload(paths, false)
+			return evicted, err
+		}
+		r.deps.graph.removeDependencies(key)
+		paths = append(paths, strings.TrimPrefix(key, r.cachePrefix+"@"))
+		if ok {
+			evicted = append(evicted, key)
+		}
+	}
+	r.deps.unload(paths, false)
+	return evicted, nil
+}
+
+// Options for a Watcher
+type WatchOptions struct {
+	// How often the files of loaded modules are checked for changes if the Vfs does not
+	// report changes itself (defaults to one second)
+	PollInterval time.Duration
+
+	// Called with the evicted cache keys whenever modules are evicted
+	OnEvict func(keys []string)
+}
+
+// A Watcher evicts modules from the require cache of a Lua VM when their files change,
+// along with the modules that (transitively) required them, so the next require reloads them
+//
+// Changes to the files of loaded modules and to the .luaurc files used to resolve them are watched.
+// Changing a .luaurc file (including creating one) evicts all modules of the requirer.
+//
+// Changes to a MemVfs are picked up through its change notifications and changes to a Vfs created
+// using NewDirVfs through the file system notifications of the OS (using fsnotify). Other Vfs
+// implementations (and a NewDirVfs if the OS watcher cannot be created) are polled for changes.
+// As the Lua VM cannot be used concurrently, changed modules are only evicted on the next require
+// through the requirer or when Flush is called.
+type Watcher struct {
+	lua      *vm.Lua
+	requirer *SimpleRequirer
+	opts     WatchOptions
+
+	mu      sync.Mutex
+	pending map[string]bool // changed paths
+
+	// Set when the OS is watched for changes
+	dir     *rootedFS
+	fsw     *fsnotify.Watcher
+	watched map[string]bool // watched OS directories
+
+	stopOnce sync.Once
+	stop     func()
+}
+
+// Watch starts watching the modules loaded by the requirer in lua for changes. Only one Watcher can
+// be active for a requirer at a time, watching again stops the previous Watcher.
+func (r *SimpleRequirer) Watch(lua *vm.Lua, opts WatchOptions) *Watcher {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+
+	w := &Watcher{
+		lua:      lua,
+		requirer: r,
+		opts:     opts,
+		pending:  make(map[string]bool),
+	}
+
+	if memVfs, ok := r.vfs.fs.(*MemVfs); ok {
+		w.stop = memVfs.OnChange(func(event MemVfsEvent) {
+			w.markChanged(event.Path)
+			if event.OldPath != "" {
+				w.markChanged(event.OldPath)
+			}
+		})
+	} else if dir := osDirOf(r.vfs.fs); dir == nil || !w.watchOS(dir) {
+		done := make(chan struct{})
+		go w.poll(done)
+		w.stop = func() { close(done) }
+	}
+
+	if old := r.watcher.Swap(w); old != nil {
+		old.Stop()
+	}
+	return w
+}
+
+// Returns the real directory of a Vfs created using NewDirVfs, or nil for other Vfs implementations
+func osDirOf(fs Vfs) *rootedFS {
+	if unixVfs, ok := fs.(*UnixVfs); ok {
+		if dir, ok := unixVfs.fs.(*rootedFS); ok && dir.dir != "" {
+			return dir
+		}
+	}
+	return nil
+}
+
+// Starts watching the OS directories of loaded files, returning false if the OS watcher cannot be created
+func (w *Watcher) watchOS(dir *rootedFS) bool {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return false
+	}
+	w.dir = dir
+	w.fsw = fsw
+	w.watched = make(map[string]bool)
+	for path := range w.requirer.deps.loadedModules() {
+		w.track(path)
+	}
+	go w.handleEvents()
+	w.stop = func() { fsw.Close() }
+	return true
+}
+
+// Watches the OS directory of a loaded file if the OS is watched for changes
+//
+// fsnotify only reports changes to the direct children of watched directories, so the directory
+// of each loaded file is watched.
+func (w *Watcher) track(path string) {
+	if w.fsw == nil {
+		return
+	}
+	dir := filepath.Dir(w.dir.osPath(path))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watched[dir] {
+		return
+	}
+	if err := w.fsw.Add(dir); err == nil {
+		w.watched[dir] = true
+	}
+}
+
+// Marks loaded files changed by OS events until the OS watcher is closed
+func (w *Watcher) handleEvents() {
+	for {
+		select {
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				// The OS stops watching removed directories, so they are watched again when needed
+				w.mu.Lock()
+				delete(w.watched, event.Name)
+				w.mu.Unlock()
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			if path, ok := w.dir.vfsPath(event.Name); ok && w.requirer.deps.isLoaded(path) {
+				w.markChanged(path)
+			}
+		case _, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// Polls the files of loaded modules (and .luaurc files) for changes until done is closed
+//
+// Files which can no longer be read are reported as changed.
+func (w *Watcher) poll(done chan struct{}) {
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			for path, hash := range w.requirer.deps.loadedModules() {
+				content, err := w.readFile(path)
+				if err != nil {
+					if hash != missingHash {
+						w.markChanged(path)
+					}
+				} else if hashContents(content) != hash {
+					w.markChanged(path)
+				}
+			}
+		}
+	}
+}
+
+func (w *Watcher) readFile(path string) ([]byte, error) {
+	file, err := w.requirer.vfs.fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func (w *Watcher) markChanged(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending[path] = true
+}
+
+// Pending returns the sorted paths of changed files which have not been evicted yet
+func (w *Watcher) Pending() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]string, 0, len(w.pending))
+	for path := range w.pending {
+		out = append(out, path)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Flush evicts the modules of all changed files (and their dependents), returning the evicted cache keys
+//
+// Flush must not be called concurrently with code running in the Lua VM.
+func (w *Watcher) Flush() ([]string, error) {
+	paths := w.Pending()
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	w.mu.Lock()
+	for _, path := range paths {
+		delete(w.pending, path)
+	}
+	w.mu.Unlock()
+
+	evicted, err := w.requirer.Invalidate(w.lua, paths...)
+	if len(evicted) > 0 && w.opts.OnEvict != nil {
+		w.opts.OnEvict(evicted)
+	}
+	return evicted, err
+}
+
+// Stop stops watching for changes. Pending changes are discarded.
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		w.stop()
+		w.requirer.watcher.CompareAndSwap(w, nil)
+	})
+}
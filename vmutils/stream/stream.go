@@ -0,0 +1,426 @@
+// Package stream exposes Go readers and writers to Luau as userdata
+//
+// Scripts can then process large inputs incrementally instead of receiving them as one string:
+//
+//	for line in input:lines() do
+//		output:write(line, "\n")
+//	end
+//	output:flush()
+//
+// Streams support the following methods:
+//
+//   - read(n): reads up to n bytes as a string (or everything that is left if n is omitted)
+//   - read(buf, offset, count): reads up to count bytes (defaulting to the rest of the buffer)
+//     into buf at offset (defaulting to 0), returning the number of bytes read
+//   - readline(keepNewline): reads a line, without its line ending unless keepNewline is true
+//   - lines(): returns an iterator over the remaining lines
+//   - write(...): writes strings, buffers and numbers, returning the number of bytes written
+//   - flush(): flushes buffered writes to the underlying io.Writer
+//   - close(): flushes and closes the stream (and the underlying io.Closer, if any)
+//
+// The read functions return nil at the end of the stream. Other Go I/O errors are raised as
+// Luau errors.
+package stream
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/koeng101/gluau/vm"
+	"github.com/koeng101/gluau/vmutils"
+)
+
+var (
+	ErrClosed      = errors.New("stream is closed")
+	ErrNotReadable = errors.New("stream is not readable")
+	ErrNotWritable = errors.New("stream is not writable")
+)
+
+// A Stream wraps an io.Reader and/or io.Writer for use from Luau
+//
+// Reads and writes are buffered. A Stream is safe for concurrent use.
+//
+// Buffered writes are not flushed when the userdata of a Stream is garbage collected (which would
+// block the collector on I/O), so scripts (or the Go code owning the Stream) must call flush or close.
+type Stream struct {
+	mu     sync.Mutex
+	r      *bufio.Reader
+	w      *bufio.Writer
+	c      io.Closer
+	closed bool
+}
+
+// New creates a Stream from v, which should implement io.Reader and/or io.Writer
+//
+// If v also implements io.Closer, it is closed when the stream is closed.
+func New(v any) *Stream {
+	s := &Stream{}
+	if r, ok := v.(io.Reader); ok {
+		s.r = bufio.NewReader(r)
+	}
+	if w, ok := v.(io.Writer); ok {
+		s.w = bufio.NewWriter(w)
+	}
+	if c, ok := v.(io.Closer); ok {
+		s.c = c
+	}
+	return s
+}
+
+// NewReader creates a read-only Stream from r
+func NewReader(r io.Reader) *Stream {
+	return New(struct{ io.Reader }{r})
+}
+
+// NewWriter creates a write-only Stream from w
+func NewWriter(w io.Writer) *Stream {
+	return New(struct{ io.Writer }{w})
+}
+
+// Returns the reader of the stream, the lock must be held
+func (s *Stream) reader() (*bufio.Reader, error) {
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if s.r == nil {
+		return nil, ErrNotReadable
+	}
+	return s.r, nil
+}
+
+// Returns the reader of the stream, or an error if the stream cannot be read from
+func (s *Stream) readable() (*bufio.Reader, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reader()
+}
+
+// Returns the writer of the stream, the lock must be held
+func (s *Stream) writer() (*bufio.Writer, error) {
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if s.w == nil {
+		return nil, ErrNotWritable
+	}
+	return s.w, nil
+}
+
+// Read reads up to n bytes, returning io.EOF at the end of the stream
+//
+// If n is negative, everything left in the stream is read.
+func (s *Stream) Read(n int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.reader()
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			return nil, io.EOF
+		}
+		return data, nil
+	}
+	if n == 0 {
+		if _, err := r.Peek(1); err != nil {
+			return nil, err
+		}
+		return []byte{}, nil
+	}
+
+	data := make([]byte, n)
+	read, err := io.ReadFull(r, data)
+	if read > 0 && (err == nil || err == io.ErrUnexpectedEOF) {
+		return data[:read], nil
+	}
+	return nil, err
+}
+
+// ReadInto reads up to len(p) bytes into p, returning io.EOF at the end of the stream
+func (s *Stream) ReadInto(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.reader()
+	if err != nil {
+		return 0, err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n, err := io.ReadFull(r, p)
+	if n > 0 && err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadLine reads the next line, returning io.EOF at the end of the stream
+//
+// The line ending ("\n" or "\r\n") is only included if keepNewline is true.
+func (s *Stream) ReadLine(keepNewline bool) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.reader()
+	if err != nil {
+		return "", err
+	}
+
+	line, err := r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	if !keepNewline {
+		line = strings.TrimSuffix(line, "\n")
+		line = strings.TrimSuffix(line, "\r")
+	}
+	return line, nil
+}
+
+// Write writes p to the stream
+func (s *Stream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, err := s.writer()
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(p)
+}
+
+// Flush writes any buffered data to the underlying io.Writer
+func (s *Stream) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, err := s.writer()
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// Close flushes the stream and closes the underlying io.Closer (if any)
+//
+// Closing a stream more than once does nothing.
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	var err error
+	if s.w != nil {
+		err = s.w.Flush()
+	}
+	if s.c != nil {
+		if cerr := s.c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// IsClosed returns whether the stream has been closed
+func (s *Stream) IsClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+var streamType = newStreamType()
+
+// Type returns the TypedUserData describing streams
+//
+// It can be used to extend streams with additional methods (e.g. using vmutils.SetParent).
+func Type() *vmutils.TypedUserData[Stream] {
+	return streamType
+}
+
+// Create creates a userdata for the stream s
+func Create(lua *vm.Lua, s *Stream) (*vm.LuaUserData, error) {
+	return streamType.Create(lua, s)
+}
+
+// Closes the arguments of a method once they have been used
+func closeArgs(args []vm.Value) {
+	for _, arg := range args {
+		if arg != nil {
+			arg.Close()
+		}
+	}
+}
+
+// Returns the optional integer argument at index (defaulting to def if it is missing or nil)
+func optInt(args *vmutils.ValueSet, index int, def int64) (int64, error) {
+	v, err := args.ValueAt(index)
+	if err != nil || v == nil || v.Type() == vm.LuaValueNil {
+		return def, nil
+	}
+	return args.IntegerAt(index)
+}
+
+// Converts the result of a read to Luau values, returning nil at the end of the stream
+func readResult(v vm.Value, err error) ([]vm.Value, error) {
+	if err == io.EOF {
+		return []vm.Value{vm.NewValueNil()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []vm.Value{v}, nil
+}
+
+func newStreamType() *vmutils.TypedUserData[Stream] {
+	tud := vmutils.NewTypedUserData[Stream]()
+	tud.SetTypeName("Stream")
+
+	tud.AddMethod("read", func(s *Stream, _ *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		defer closeArgs(args)
+		vs := vmutils.NewValueSet(args)
+
+		if len(args) > 0 {
+			if buf, ok := args[0].(*vm.ValueBuffer); ok {
+				offset, err := optInt(vs, 1, 0)
+				if err != nil {
+					return nil, err
+				}
+				count, err := optInt(vs, 2, -1)
+				if err != nil {
+					return nil, err
+				}
+				size := int64(buf.Value().Len())
+				if offset < 0 || offset > size {
+					return nil, fmt.Errorf("offset %d is out of bounds for buffer of length %d", offset, size)
+				}
+				end := size
+				if count >= 0 {
+					if count > end-offset {
+						return nil, fmt.Errorf("count %d at offset %d is out of bounds for buffer of length %d", count, offset, size)
+					}
+					end = offset + count
+				}
+				// Read into a Go slice so the buffer is not held while blocking on I/O
+				scratch := make([]byte, end-offset)
+				n, err := s.ReadInto(scratch)
+				if n > 0 {
+					if err := buf.Value().WriteBytes(uint64(offset), scratch[:n]); err != nil {
+						return nil, err
+					}
+				}
+				return readResult(vm.NewValueNumber(float64(n)), err)
+			}
+		}
+
+		n, err := optInt(vs, 0, -1)
+		if err != nil {
+			return nil, err
+		}
+		if n < -1 {
+			return nil, fmt.Errorf("cannot read a negative number of bytes (%d)", n)
+		}
+		data, err := s.Read(int(n))
+		return readResult(vm.GoString(data), err)
+	})
+
+	tud.AddMethod("readline", func(s *Stream, _ *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		defer closeArgs(args)
+		keepNewline := false
+		if len(args) > 0 && args[0] != nil && args[0].Type() != vm.LuaValueNil {
+			var err error
+			keepNewline, err = vmutils.NewValueSet(args).BoolAt(0)
+			if err != nil {
+				return nil, err
+			}
+		}
+		line, err := s.ReadLine(keepNewline)
+		return readResult(vm.GoString(line), err)
+	})
+
+	tud.AddMethod("lines", func(s *Stream, funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		closeArgs(args)
+		if _, err := s.readable(); err != nil {
+			return nil, err
+		}
+		iter, err := funcVm.MainState().CreateFunction(func(_ *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+			closeArgs(args)
+			line, err := s.ReadLine(false)
+			return readResult(vm.GoString(line), err)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []vm.Value{iter.ToValue()}, nil
+	})
+
+	tud.AddMethod("write", func(s *Stream, _ *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		defer closeArgs(args)
+		written := 0
+		var scratch []byte // Strings and buffers are copied so they are not held while blocking on I/O
+		copyBytes := func(b []byte) error {
+			scratch = append(scratch[:0], b...)
+			return nil
+		}
+		for i, arg := range args {
+			var n int
+			var err error
+			switch v := arg.(type) {
+			case *vm.ValueString:
+				if err = v.Value().WithBytes(copyBytes); err == nil {
+					n, err = s.Write(scratch)
+				}
+			case vm.GoString:
+				n, err = s.Write([]byte(v))
+			case *vm.ValueBuffer:
+				if err = v.Value().WithBytes(copyBytes); err == nil {
+					n, err = s.Write(scratch)
+				}
+			case *vm.ValueInteger:
+				n, err = s.Write([]byte(strconv.FormatInt(v.Value(), 10)))
+			case *vm.ValueNumber:
+				n, err = s.Write([]byte(strconv.FormatFloat(v.Value(), 'g', 14, 64)))
+			default:
+				typ := "nil"
+				if arg != nil {
+					typ = arg.Type().String()
+				}
+				return nil, vmutils.TypeMismatchError(i+1, "string or buffer", typ)
+			}
+			written += n
+			if err != nil {
+				return nil, err
+			}
+		}
+		return []vm.Value{vm.NewValueNumber(float64(written))}, nil
+	})
+
+	tud.AddMethod("flush", func(s *Stream, _ *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		closeArgs(args)
+		return nil, s.Flush()
+	})
+
+	tud.AddMethod("close", func(s *Stream, _ *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+		closeArgs(args)
+		return nil, s.Close()
+	})
+
+	tud.SetToString(func(s *Stream) (string, error) {
+		if s.IsClosed() {
+			return "Stream (closed)", nil
+		}
+		return "Stream", nil
+	})
+
+	return tud
+}
@@ -3,21 +3,33 @@ package vmutils
 import (
 	"errors"
 	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
 
 	"github.com/koeng101/gluau/vm"
 )
 
 // Ergonomic userdata handling
 //
-// WARNING: This is an experimental and completely untested feature.
+// A TypedUserData only describes the user data and can be shared between Lua VMs. The
+// metatable is created the first time Create is called with a VM and stored in the registry
+// of that VM, so the TypedUserData does not keep the VM alive.
 type TypedUserData[T any] struct {
-	mt           *vm.LuaTable                                                         // cached/shared metatable for the user data
+	mtMu         sync.Mutex
+	registryKey  string                                                               // registry key of the metatable in each Lua VM
 	fields       map[string]vm.Value                                                  // fields of the user data
-	fieldGetters map[string]func(*T) (vm.Value, error)                                // field getters
+	fieldGetters map[string]func(*T, *vm.CallbackLua) (vm.Value, error)               // field getters
 	fieldSetters map[string]func(*T, *vm.CallbackLua, vm.Value) error                 // field setters
 	methods      map[string]func(*T, *vm.CallbackLua, []vm.Value) ([]vm.Value, error) // methods of the user data
 	typename     string                                                               // type name of the user data
 	metamethods  map[string]func(*T, *vm.CallbackLua, []vm.Value) ([]vm.Value, error) // metamethods
+	operators    map[string]*operator[T]                                              // typed binary operators
+	onCollect    func(*T)                                                             // called when the user data is collected
+	inherited    func() *TypedUserData[T]                                             // members inherited from the parent (set by SetParent)
+	collectHook  func(*T)                                                             // onCollect including inheritance (set when the first metatable is created)
+	origin       *TypedUserData[T]                                                    // the TypedUserData a flattened copy was created from
 }
 
 // Parse the first value as a TypedUserData of type T returning the data and the remaining values
@@ -35,22 +47,33 @@ func ParseSelf[T any](typeName string, values []vm.Value) (*T, []vm.Value, error
 	if err != nil {
 		return nil, nil, err
 	}
-	dataT, ok := data.(*T)
-	if !ok {
-		return nil, nil, TypeMismatchError(0, "userdata of type "+typeName, "userdata")
+	if dataT, ok := data.(*T); ok {
+		return dataT, values[1:], nil
 	}
 
-	return dataT, values[1:], nil
+	// If T is an interface, accept any value implementing it
+	if reflect.TypeOf((*T)(nil)).Elem().Kind() == reflect.Interface {
+		if iface, ok := data.(T); ok {
+			return &iface, values[1:], nil
+		}
+	}
+
+	return nil, nil, TypeMismatchError(0, "userdata of type "+typeName, "userdata")
 }
 
 // Adds a field to the TypedUserData
+//
+// The value is copied into the metatable of every VM the user data is created in, so
+// handles (tables, functions etc.) can only be used with the VM they belong to.
 func (tud *TypedUserData[T]) AddField(name string, value vm.Value) {
 	tud.fields[name] = value
 }
 
 // Adds a field getter to the TypedUserData
 func (tud *TypedUserData[T]) AddFieldGetter(name string, getter func(*T) (vm.Value, error)) {
-	tud.fieldGetters[name] = getter
+	tud.fieldGetters[name] = func(self *T, _ *vm.CallbackLua) (vm.Value, error) {
+		return getter(self)
+	}
 }
 
 // Adds a field setter to the TypedUserData
@@ -73,6 +96,209 @@ func (tud *TypedUserData[T]) AddMetamethod(name string, method func(*T, *vm.Call
 	tud.metamethods[name] = method
 }
 
+// Sets the parent of a TypedUserData
+//
+// The child inherits the fields, field getters/setters, methods, metamethods and operators of the
+// parent (and its ancestors) that it does not define itself. typeof still reports the type name of
+// the child. Inherited operators produce values of the parent type, and the OnCollect functions of
+// the parent are called after the child's. upcast converts the data of the child to the data of the parent and may be nil if P is an
+// interface implemented by *T or if T embeds P (or *P).
+//
+// The parent must be fully set up before the child is first used to create user data.
+func SetParent[T, P any](child *TypedUserData[T], parent *TypedUserData[P], upcast func(*T) *P) error {
+	if any(child) == any(parent) {
+		return errors.New("a TypedUserData cannot be its own parent")
+	}
+	if upcast == nil {
+		var err error
+		upcast, err = defaultUpcast[T, P]()
+		if err != nil {
+			return err
+		}
+	}
+
+	child.inherited = func() *TypedUserData[T] {
+		return inheritMembers(parent.flattened(), upcast)
+	}
+	return nil
+}
+
+// Returns the upcast used by SetParent when none is provided
+func defaultUpcast[T, P any]() (func(*T) *P, error) {
+	childType := reflect.TypeOf((*T)(nil)).Elem()
+	parentType := reflect.TypeOf((*P)(nil)).Elem()
+
+	if parentType.Kind() == reflect.Interface && reflect.PointerTo(childType).Implements(parentType) {
+		return func(data *T) *P {
+			iface := any(data).(P)
+			return &iface
+		}, nil
+	}
+
+	if childType.Kind() == reflect.Struct {
+		for i := 0; i < childType.NumField(); i++ {
+			field := childType.Field(i)
+			if !field.Anonymous || !field.IsExported() {
+				continue
+			}
+			index := i // Capture the loop variable
+			switch field.Type {
+			case parentType:
+				return func(data *T) *P {
+					return reflect.ValueOf(data).Elem().Field(index).Addr().Interface().(*P)
+				}, nil
+			case reflect.PointerTo(parentType):
+				return func(data *T) *P {
+					return reflect.ValueOf(data).Elem().Field(index).Interface().(*P)
+				}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("cannot convert %s to %s, an upcast function must be provided", childType, parentType)
+}
+
+// Converts the members of a parent to members of a child using upcast
+func inheritMembers[T, P any](parent *TypedUserData[P], upcast func(*T) *P) *TypedUserData[T] {
+	members := NewTypedUserData[T]()
+
+	parentOf := func(self *T) (*P, error) {
+		p := upcast(self)
+		if p == nil {
+			return nil, errors.New("parent of user data is nil")
+		}
+		return p, nil
+	}
+
+	for key, value := range parent.fields {
+		members.fields[key] = value
+	}
+	for key, getter := range parent.fieldGetters {
+		getter := getter // Capture the loop variable
+		members.fieldGetters[key] = func(self *T, funcVm *vm.CallbackLua) (vm.Value, error) {
+			p, err := parentOf(self)
+			if err != nil {
+				return nil, err
+			}
+			return getter(p, funcVm)
+		}
+	}
+	for key, setter := range parent.fieldSetters {
+		setter := setter // Capture the loop variable
+		members.fieldSetters[key] = func(self *T, funcVm *vm.CallbackLua, value vm.Value) error {
+			p, err := parentOf(self)
+			if err != nil {
+				return err
+			}
+			return setter(p, funcVm, value)
+		}
+	}
+	inheritMethods := func(dst map[string]func(*T, *vm.CallbackLua, []vm.Value) ([]vm.Value, error), src map[string]func(*P, *vm.CallbackLua, []vm.Value) ([]vm.Value, error)) {
+		for key, method := range src {
+			method := method // Capture the loop variable
+			dst[key] = func(self *T, funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
+				p, err := parentOf(self)
+				if err != nil {
+					return nil, err
+				}
+				return method(p, funcVm, args)
+			}
+		}
+	}
+	inheritMethods(members.methods, parent.methods)
+	inheritMethods(members.metamethods, parent.metamethods)
+	for key, op := range parent.operators {
+		key, op := key, op // Capture the loop variables
+		members.operators[key] = &operator[T]{
+			other: func(funcVm *vm.CallbackLua, a, b Operand[T]) ([]vm.Value, error) {
+				// Operands of the child are passed to the parent as its own data
+				parentOperand := func(o Operand[T]) Operand[P] {
+					po := operandOf[P](o.Value)
+					if o.Self != nil {
+						po.Self = upcast(o.Self)
+					}
+					return po
+				}
+				return parent.callOperator(funcVm, key, op, parentOperand(a), parentOperand(b))
+			},
+		}
+	}
+	if parent.onCollect != nil {
+		members.onCollect = func(self *T) {
+			if p := upcast(self); p != nil {
+				parent.onCollect(p)
+			}
+		}
+	}
+
+	return members
+}
+
+// Returns a copy of the TypedUserData including the members inherited from its parent
+func (tud *TypedUserData[T]) flattened() *TypedUserData[T] {
+	flat := NewTypedUserData[T]()
+	flat.typename = tud.typename
+	flat.origin = tud
+	if tud.inherited != nil {
+		inherited := tud.inherited()
+		flat.fields = inherited.fields
+		flat.fieldGetters = inherited.fieldGetters
+		flat.fieldSetters = inherited.fieldSetters
+		flat.methods = inherited.methods
+		flat.metamethods = inherited.metamethods
+		flat.operators = inherited.operators
+		flat.onCollect = inherited.onCollect
+	}
+	if own, inherited := tud.onCollect, flat.onCollect; own != nil && inherited != nil {
+		// The hook of the child runs first, as the parent's may release what the child's uses
+		flat.onCollect = func(self *T) {
+			own(self)
+			inherited(self)
+		}
+	} else if own != nil {
+		flat.onCollect = own
+	}
+
+	// Own members shadow inherited members of the same name
+	shadow := func(name string) {
+		delete(flat.fields, name)
+		delete(flat.fieldGetters, name)
+		delete(flat.methods, name)
+	}
+	for key, value := range tud.fields {
+		shadow(key)
+		flat.fields[key] = value
+	}
+	for key, getter := range tud.fieldGetters {
+		shadow(key)
+		flat.fieldGetters[key] = getter
+	}
+	for key, method := range tud.methods {
+		shadow(key)
+		flat.methods[key] = method
+	}
+	for key, setter := range tud.fieldSetters {
+		flat.fieldSetters[key] = setter
+	}
+	for key, method := range tud.metamethods {
+		delete(flat.operators, key) // Operators are set after metamethods
+		flat.metamethods[key] = method
+	}
+	for key, op := range tud.operators {
+		flat.operators[key] = op
+	}
+	return flat
+}
+
+// Sets a function to be called when user data created by this TypedUserData is collected by Luau
+// (or the Lua VM it belongs to is closed), e.g. to release resources owned by the data
+//
+// The Lua VM must not be used from within fn as it may be called from within the garbage collector.
+// The OnCollect functions of the ancestors set using SetParent are called after fn.
+func (tud *TypedUserData[T]) OnCollect(fn func(*T)) {
+	tud.onCollect = fn
+}
+
 // Returns `true` if the __index metamethod should be a table
 //
 // A fastpath is only allowed if there are no field getters,
@@ -92,29 +318,92 @@ func (tud *TypedUserData[T]) indexFastPath() bool {
 
 // Creates a new UserData
 func (tud *TypedUserData[T]) Create(lua *vm.Lua, data *T) (*vm.LuaUserData, error) {
-	if tud.mt == nil {
-		if tud.indexFastPath() {
-			mt, err := tud.createMtFast(lua)
-			if err != nil {
-				return nil, err
-			}
-			tud.mt = mt
-		} else {
-			mt, err := tud.createMtSlow(lua)
-			if err != nil {
-				return nil, err
-			}
-			tud.mt = mt
+	mt, onCollect, err := tud.metatable(lua)
+	if err != nil {
+		return nil, err
+	}
+	defer mt.Close()
+
+	var opts vm.UserDataOpts
+	if onCollect != nil {
+		opts.OnCollect = func(any) {
+			onCollect(data)
 		}
 	}
 
-	ud, err := lua.CreateUserData(data, tud.mt)
+	ud, err := lua.CreateUserDataOpts(data, mt, opts)
 	if err != nil {
 		return nil, err
 	}
 	return ud, nil
 }
 
+// Counter used to give every TypedUserData its own registry key
+var typedUserDataIDs atomic.Uint64
+
+// Returns the metatable for a Lua VM (creating it if needed) and the collect hook of the user
+// data (including the inherited one). The caller must close the metatable.
+//
+// The metatable is kept in the registry of the VM rather than in the TypedUserData, as caching
+// it here would keep VMs that are never closed from being garbage collected.
+func (tud *TypedUserData[T]) metatable(lua *vm.Lua) (*vm.LuaTable, func(*T), error) {
+	tud.mtMu.Lock()
+	defer tud.mtMu.Unlock()
+
+	if tud.registryKey == "" {
+		tud.registryKey = "gluau.TypedUserData." + strconv.FormatUint(typedUserDataIDs.Add(1), 10)
+	}
+
+	cached, err := lua.RegistryValue(tud.registryKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mt, ok := cached.(*vm.ValueTable); ok {
+		return mt.Value(), tud.collectHook, nil
+	}
+	cached.Close()
+
+	flat := tud.flattened()
+	mt, err := flat.createMetatable(lua)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := lua.SetRegistryValue(tud.registryKey, mt.ToValue().Clone()); err != nil {
+		mt.Close()
+		return nil, nil, err
+	}
+	tud.collectHook = flat.onCollect
+	return mt, tud.collectHook, nil
+}
+
+// Creates the metatable of the user data for a Lua VM
+//
+// Inherited members are not taken into account, so this should be called on a flattened TypedUserData
+func (tud *TypedUserData[T]) createMetatable(lua *vm.Lua) (*vm.LuaTable, error) {
+	if _, ok := tud.metamethods["__tostring"]; !ok {
+		if toString := stringerToString[T](); toString != nil {
+			tud.metamethods["__tostring"] = toString
+		}
+	}
+
+	if tud.indexFastPath() {
+		return tud.createMtFast(lua)
+	}
+	return tud.createMtSlow(lua)
+}
+
+// Returns the key passed to __index/__newindex as a string
+func indexKey(key vm.Value) (string, error) {
+	switch k := key.(type) {
+	case *vm.ValueString:
+		return k.Value().String(), nil
+	case vm.GoString:
+		return string(k), nil
+	default:
+		return "", TypeMismatchError(1, "string", key.Type().String())
+	}
+}
+
 func (tud *TypedUserData[T]) createMtFast(lua *vm.Lua) (*vm.LuaTable, error) {
 	typeName := tud.typename
 
@@ -132,6 +421,7 @@ func (tud *TypedUserData[T]) createMtFast(lua *vm.Lua) (*vm.LuaTable, error) {
 	}
 
 	for key, value := range tud.metamethods {
+		value := value // Capture the loop variable
 		callback := func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
 			self, args, err := ParseSelf[T](typeName, args)
 			if err != nil {
@@ -149,6 +439,10 @@ func (tud *TypedUserData[T]) createMtFast(lua *vm.Lua) (*vm.LuaTable, error) {
 		}
 	}
 
+	if err := tud.setOperators(lua, udMt); err != nil {
+		return nil, err
+	}
+
 	// fastpath doesnt support field getters or setters, so we can just use a table
 	indexMt, err := lua.CreateTable()
 	if err != nil {
@@ -156,12 +450,14 @@ func (tud *TypedUserData[T]) createMtFast(lua *vm.Lua) (*vm.LuaTable, error) {
 	}
 
 	for key, value := range tud.fields {
-		if err := indexMt.Set(vm.GoString(key), value); err != nil {
+		// Set takes ownership of the value so a clone is used to keep the field usable for other VMs
+		if err := indexMt.Set(vm.GoString(key), value.Clone()); err != nil {
 			return nil, err
 		}
 	}
 
 	for key, method := range tud.methods {
+		method := method // Capture the loop variable
 		callback := func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
 			self, args, err := ParseSelf[T](typeName, args)
 			if err != nil {
@@ -194,15 +490,15 @@ func (tud *TypedUserData[T]) createMtFast(lua *vm.Lua) (*vm.LuaTable, error) {
 				return nil, errors.New("expected 2 arguments for __newindex, got " + fmt.Sprint(len(args)))
 			}
 
-			fieldName, ok := args[0].(vm.GoString)
-			if !ok {
-				return nil, TypeMismatchError(0, "string", args[0].Type().String())
+			fieldName, err := indexKey(args[0])
+			if err != nil {
+				return nil, err
 			}
 
 			value := args[1]
-			setter, ok := tud.fieldSetters[string(fieldName)]
+			setter, ok := tud.fieldSetters[fieldName]
 			if !ok {
-				return nil, errors.New("no setter defined for field " + string(fieldName))
+				return nil, errors.New("no setter defined for field " + fieldName)
 			}
 
 			if err := setter(self, funcVm, value); err != nil {
@@ -242,6 +538,7 @@ func (tud *TypedUserData[T]) createMtSlow(lua *vm.Lua) (*vm.LuaTable, error) {
 	}
 
 	for key, value := range tud.metamethods {
+		value := value // Capture the loop variable
 		if key == "__index" {
 			continue
 		}
@@ -263,31 +560,13 @@ func (tud *TypedUserData[T]) createMtSlow(lua *vm.Lua) (*vm.LuaTable, error) {
 		}
 	}
 
-	// Create the field getter functions once
-	fieldGetterFuncs := make(map[string]*vm.LuaFunction)
-	for key, getter := range tud.fieldGetters {
-		callback := func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
-			self, _, err := ParseSelf[T](typeName, args)
-			if err != nil {
-				return nil, err
-			}
-
-			value, err := getter(self)
-			if err != nil {
-				return nil, err
-			}
-
-			return []vm.Value{value}, nil
-		}
-		funct, err := lua.CreateFunction(callback)
-		if err != nil {
-			return nil, err
-		}
-		fieldGetterFuncs[key] = funct
+	if err := tud.setOperators(lua, udMt); err != nil {
+		return nil, err
 	}
 
 	var methodFuncs = make(map[string]*vm.LuaFunction)
 	for key, method := range tud.methods {
+		method := method // Capture the loop variable
 		callback := func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
 			self, args, err := ParseSelf[T](typeName, args)
 			if err != nil {
@@ -304,7 +583,7 @@ func (tud *TypedUserData[T]) createMtSlow(lua *vm.Lua) (*vm.LuaTable, error) {
 	}
 
 	indexCallback := func(funcVm *vm.CallbackLua, args []vm.Value) ([]vm.Value, error) {
-		_, args, err := ParseSelf[T](typeName, args)
+		self, args, err := ParseSelf[T](typeName, args)
 		if err != nil {
 			return nil, err
 		}
@@ -313,20 +592,28 @@ func (tud *TypedUserData[T]) createMtSlow(lua *vm.Lua) (*vm.LuaTable, error) {
 		if len(args) < 1 {
 			return nil, errors.New("expected at least 1 argument for __index, got " + fmt.Sprint(len(args)))
 		}
-		fieldName, ok := args[0].(*vm.ValueString)
-		if !ok {
-			return nil, TypeMismatchError(0, "string", args[0].Type().String())
+		fieldName, err := indexKey(args[0])
+		if err != nil {
+			return nil, err
 		}
-		if fieldGetter, ok := fieldGetterFuncs[fieldName.Value().String()]; ok {
-			return []vm.Value{fieldGetter.ToValue()}, nil
+		if fieldGetter, ok := tud.fieldGetters[fieldName]; ok {
+			value, err := fieldGetter(self, funcVm)
+			if err != nil {
+				return nil, err
+			}
+			return []vm.Value{value}, nil
+		}
+
+		// Check static fields and methods (returning a clone as returning a value takes ownership of it)
+		if value, ok := tud.fields[fieldName]; ok {
+			return []vm.Value{value.Clone()}, nil
 		}
 
-		// Check methods
-		if methods, ok := methodFuncs[fieldName.Value().String()]; ok {
-			return []vm.Value{methods.ToValue()}, nil
+		if method, ok := methodFuncs[fieldName]; ok {
+			return []vm.Value{method.ToValue().Clone()}, nil
 		}
 
-		return nil, errors.New("no field or method found for " + fieldName.Value().String())
+		return nil, errors.New("no field or method found for " + fieldName)
 	}
 
 	// Set index metamethod
@@ -349,15 +636,15 @@ func (tud *TypedUserData[T]) createMtSlow(lua *vm.Lua) (*vm.LuaTable, error) {
 				return nil, errors.New("expected 2 arguments for __newindex, got " + fmt.Sprint(len(args)))
 			}
 
-			fieldName, ok := args[0].(vm.GoString)
-			if !ok {
-				return nil, TypeMismatchError(0, "string", args[0].Type().String())
+			fieldName, err := indexKey(args[0])
+			if err != nil {
+				return nil, err
 			}
 
 			value := args[1]
-			setter, ok := tud.fieldSetters[string(fieldName)]
+			setter, ok := tud.fieldSetters[fieldName]
 			if !ok {
-				return nil, errors.New("no setter defined for field " + string(fieldName))
+				return nil, errors.New("no setter defined for field " + fieldName)
 			}
 
 			if err := setter(self, funcVm, value); err != nil {
@@ -384,10 +671,11 @@ func (tud *TypedUserData[T]) createMtSlow(lua *vm.Lua) (*vm.LuaTable, error) {
 func NewTypedUserData[T any]() *TypedUserData[T] {
 	return &TypedUserData[T]{
 		fields:       make(map[string]vm.Value),
-		fieldGetters: make(map[string]func(*T) (vm.Value, error)),
+		fieldGetters: make(map[string]func(*T, *vm.CallbackLua) (vm.Value, error)),
 		fieldSetters: make(map[string]func(*T, *vm.CallbackLua, vm.Value) error),
 		methods:      make(map[string]func(*T, *vm.CallbackLua, []vm.Value) ([]vm.Value, error)),
 		typename:     "",
 		metamethods:  make(map[string]func(*T, *vm.CallbackLua, []vm.Value) ([]vm.Value, error)),
+		operators:    make(map[string]*operator[T]),
 	}
 }